@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/spec"
+)
+
+var (
+	applyFile    string
+	applyDryRun  bool
+	applyYes     bool
+	applyRestart bool
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply -f <spec.yaml>",
+	Short: "Converge a server to a declarative spec file",
+	Long: `Converge a server to a declarative YAML spec file, which can be kept in git.
+The spec is compared with the server configuration and the files in the server
+directory, and the plan of changes is shown before anything is changed: the
+server is created if it does not exist, upgraded if it is on another version,
+and its settings, server.properties, ops, whitelist, plugins or mods, schedules
+and backup retention are brought in line with the spec. Applying a spec that
+has not changed changes nothing.
+
+A running server is only changed with --restart, which stops it for the
+changes and starts it again afterwards. See the documentation for the format
+of the spec file.
+
+Example:
+  mcsrvr apply -f survival.yaml --dry-run
+  mcsrvr apply -f survival.yaml
+  mcsrvr apply -f survival.yaml -y --restart`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := spec.Load(applyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Compare the spec with the server
+		steps, err := spec.Plan(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to plan changes: %v\n", err)
+			os.Exit(1)
+		}
+		if len(steps) == 0 {
+			fmt.Printf("Server '%s' matches the spec, nothing to do.\n", s.Name)
+			return
+		}
+
+		// Show the plan
+		fmt.Printf("Plan for server '%s':\n", s.Name)
+		for _, step := range steps {
+			fmt.Printf("  %s\n", step.Description)
+		}
+		if applyDryRun {
+			return
+		}
+
+		// Confirm the changes if not forced
+		if !applyYes {
+			fmt.Printf("Apply %d changes to server '%s'? (y/N): ", len(steps), s.Name)
+			reader := bufio.NewReader(os.Stdin)
+			response, err := reader.ReadString('\n')
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+				os.Exit(1)
+			}
+
+			response = strings.TrimSpace(strings.ToLower(response))
+			if response != "y" && response != "yes" {
+				fmt.Println("Apply cancelled.")
+				return
+			}
+		}
+
+		// Converge the server
+		options := spec.Options{BackupPath: resolveBackupPath(), Restart: applyRestart}
+		if err := spec.Apply(s, steps, options); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to apply spec: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Server '%s' now matches the spec\n", s.Name)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	// Define flags for the apply command
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "Spec file to apply (required)")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show the plan without changing anything")
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "Skip confirmation prompt")
+	applyCmd.Flags().BoolVar(&applyRestart, "restart", false, "Stop a running server for the changes and start it again afterwards")
+	applyCmd.Flags().StringVar(&backupPath, "path", "", "Path of the backup repository used for the backup before an upgrade")
+
+	// Mark required flags
+	applyCmd.MarkFlagRequired("file")
+}
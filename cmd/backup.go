@@ -3,14 +3,18 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/backup"
+	"github.com/0v3rr1de0/mcsrvr/pkg/util"
 )
 
 var (
-	backupPath string
+	backupPath  string
+	pruneDryRun bool
 )
 
 // backupCmd represents the backup command
@@ -18,7 +22,9 @@ var backupCmd = &cobra.Command{
 	Use:   "backup [server-name]",
 	Short: "Create a backup of a Minecraft server",
 	Long: `Create a backup of a Minecraft server by name.
-If no backup path is provided, the backup will be created in the default backup directory.
+Backups are stored in a deduplicating repository: files are split into chunks that
+are stored once by hash, so unchanged parts of the world take no extra space.
+If no backup path is provided, the default backup repository is used.
 
 Example:
   mcsrvr backup paper123
@@ -27,18 +33,8 @@ Example:
 	Run: func(cmd *cobra.Command, args []string) {
 		serverName := args[0]
 
-		// If no backup path is provided, use the default
-		if backupPath == "" {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: Failed to get user home directory: %v\n", err)
-				os.Exit(1)
-			}
-			backupPath = filepath.Join(homeDir, ".mcsrvr", "backups")
-		}
-
 		// Create the backup
-		if err := server.CreateBackup(serverName, backupPath); err != nil {
+		if err := server.CreateBackup(serverName, resolveBackupPath()); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to create backup: %v\n", err)
 			os.Exit(1)
 		}
@@ -51,10 +47,13 @@ var backupsCmd = &cobra.Command{
 	Short: "List backups for a Minecraft server",
 	Long: `List backups for a Minecraft server by name.
 If no server name is provided, all backups will be listed.
+For each backup the logical size, the stored size of the chunks it references
+and the size only it references (freed when it is deleted) are shown.
 
 Example:
   mcsrvr backups
-  mcsrvr backups paper123`,
+  mcsrvr backups paper123
+  mcsrvr backups prune paper123 --dry-run`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		var serverName string
@@ -63,7 +62,7 @@ Example:
 		}
 
 		// List the backups
-		backups, err := server.ListBackups(serverName)
+		backups, err := server.ListBackups(resolveBackupPath(), serverName)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to list backups: %v\n", err)
 			os.Exit(1)
@@ -79,39 +78,93 @@ Example:
 		}
 
 		// Print the backups
-		fmt.Println("Backups:")
-		for _, backup := range backups {
-			fmt.Println(backup)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "BACKUP\tSERVER\tCREATED\tFILES\tLOGICAL\tSTORED\tUNIQUE")
+		for _, b := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				b.ID, b.Server, b.CreatedAt.Format(time.RFC1123), b.Files,
+				util.FormatSize(b.LogicalSize), util.FormatSize(b.StoredSize), util.FormatSize(b.UniqueSize))
+		}
+		w.Flush()
+	},
+}
+
+// pruneBackupsCmd represents the backups prune command
+var pruneBackupsCmd = &cobra.Command{
+	Use:   "prune [server-name]",
+	Short: "Delete backups outside the retention policy",
+	Long: `Delete backups that fall outside the retention policy of a server.
+If no server name is provided, every server is pruned with its own policy.
+Servers without a retention policy keep all of their backups.
+Set a policy with 'mcsrvr config [server-name] retention'.
+
+Example:
+  mcsrvr backups prune --dry-run
+  mcsrvr backups prune paper123`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var serverName string
+		if len(args) > 0 {
+			serverName = args[0]
+		}
+
+		// Prune the backups
+		if err := server.PruneBackups(resolveBackupPath(), serverName, pruneDryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to prune backups: %v\n", err)
+			os.Exit(1)
 		}
 	},
 }
 
 // restoreCmd represents the restore command
 var restoreCmd = &cobra.Command{
-	Use:   "restore [backup-path] [restore-path]",
+	Use:   "restore [backup] [restore-path]",
 	Short: "Restore a backup of a Minecraft server",
 	Long: `Restore a backup of a Minecraft server to a new location.
+The backup is the name shown by 'mcsrvr backups'. Zip archives created by
+earlier versions of mcsrvr can be restored by passing their path.
+Every restored file is checked against the hash recorded in the backup.
 
 Example:
-  mcsrvr restore ~/.mcsrvr/backups/paper123_2025-03-02_00-00-00 D:/servers/restored_paper123`,
+  mcsrvr restore paper123_2025-03-02_00-00-00 D:/servers/restored_paper123
+  mcsrvr restore paper123_2025-03-02_00-00-00 D:/servers/restored_paper123 --path D:/backups`,
 	Args: cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
-		backupPath := args[0]
+		backupName := args[0]
 		restorePath := args[1]
 
 		// Restore the backup
-		if err := server.RestoreBackup(backupPath, restorePath); err != nil {
+		if err := server.RestoreBackup(resolveBackupPath(), backupName, restorePath); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to restore backup: %v\n", err)
 			os.Exit(1)
 		}
 	},
 }
 
+// resolveBackupPath returns the backup repository given with --path, or the default one
+func resolveBackupPath() string {
+	if backupPath != "" {
+		return backupPath
+	}
+
+	defaultPath, err := backup.DefaultRepository()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return defaultPath
+}
+
 func init() {
 	rootCmd.AddCommand(backupCmd)
 	rootCmd.AddCommand(backupsCmd)
 	rootCmd.AddCommand(restoreCmd)
-
-	// Define flags for the backup command
-	backupCmd.Flags().StringVar(&backupPath, "path", "", "Path to store the backup")
+	backupsCmd.AddCommand(pruneBackupsCmd)
+
+	// Define flags for the backup commands
+	backupCmd.Flags().StringVar(&backupPath, "path", "", "Path of the backup repository")
+	backupsCmd.Flags().StringVar(&backupPath, "path", "", "Path of the backup repository")
+	restoreCmd.Flags().StringVar(&backupPath, "path", "", "Path of the backup repository")
+	pruneBackupsCmd.Flags().StringVar(&backupPath, "path", "", "Path of the backup repository")
+	pruneBackupsCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show which backups would be deleted without deleting them")
 }
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+	"github.com/0v3rr1de0/mcsrvr/pkg/util"
+)
+
+var (
+	cacheUnusedFor time.Duration
+	cacheDryRun    bool
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the download cache of server jars",
+	Long: `Manage the download cache of server jars.
+Every jar downloaded by 'mcsrvr init' is kept in the cache, keyed by provider,
+version, build and hash, so servers on the same version share one download.
+Use 'mcsrvr init --offline' to initialize servers from the cache only.
+
+Example:
+  mcsrvr cache list
+  mcsrvr cache clean vanilla
+  mcsrvr cache clean --unused-for 720h`,
+}
+
+// cacheListCmd represents the cache list command
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the cached server jars",
+	Long: `List the server jars in the download cache.
+
+Example:
+  mcsrvr cache list`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := downloader.ListCache()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to list cache: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("The download cache is empty.")
+			return
+		}
+
+		// Print the cached jars
+		var total int64
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROVIDER\tVERSION\tBUILD\tFILE\tSIZE\tLAST USED\tHASH")
+		for _, e := range entries {
+			build := e.Build
+			if build == "" {
+				build = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s:%s\n",
+				e.Provider, e.Version, build, e.FileName, util.FormatSize(e.Size),
+				e.LastUsed.Format(time.RFC1123), e.Checksum.Algorithm, shortHash(e.Checksum.Value))
+			total += e.Size
+		}
+		w.Flush()
+
+		fmt.Printf("\n%d jars, %s in %s\n", len(entries), util.FormatSize(total), downloader.CacheDir())
+	},
+}
+
+// cacheCleanCmd represents the cache clean command
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean [provider] [version]",
+	Short: "Remove server jars from the download cache",
+	Long: `Remove server jars from the download cache.
+Without arguments the whole cache is removed, including unfinished downloads.
+A provider, and optionally a version, limit which jars are removed.
+With --unused-for, only jars that have not been used for that long are removed.
+
+Example:
+  mcsrvr cache clean
+  mcsrvr cache clean papermc 1.21.4
+  mcsrvr cache clean --unused-for 720h --dry-run`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var provider, version string
+		if len(args) > 0 {
+			provider = args[0]
+		}
+		if len(args) > 1 {
+			version = args[1]
+		}
+
+		// Remove everything at once, which also clears unfinished downloads
+		if provider == "" && cacheUnusedFor == 0 && !cacheDryRun {
+			if err := os.RemoveAll(downloader.CacheDir()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to clean cache: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Download cache cleaned")
+			return
+		}
+
+		entries, err := downloader.ListCache()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to list cache: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Remove the matching jars
+		var removed int
+		var freed int64
+		for _, e := range entries {
+			if provider != "" && e.Provider != provider {
+				continue
+			}
+			if version != "" && e.Version != version {
+				continue
+			}
+			if cacheUnusedFor > 0 && time.Since(e.LastUsed) < cacheUnusedFor {
+				continue
+			}
+
+			if cacheDryRun {
+				fmt.Printf("Would remove %s\n", e.FileName)
+			} else {
+				if err := downloader.RemoveCached(e); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: Failed to remove %s: %v\n", e.FileName, err)
+					continue
+				}
+				fmt.Printf("Removed %s\n", e.FileName)
+			}
+			removed++
+			freed += e.Size
+		}
+
+		if cacheDryRun {
+			fmt.Printf("%d jars (%s) would be removed\n", removed, util.FormatSize(freed))
+		} else {
+			fmt.Printf("Removed %d jars, freed %s\n", removed, util.FormatSize(freed))
+		}
+	},
+}
+
+// shortHash shortens a hash for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+
+	// Define flags for the cache clean command
+	cacheCleanCmd.Flags().DurationVar(&cacheUnusedFor, "unused-for", 0, "Only remove jars that have not been used for this long, e.g. 720h")
+	cacheCleanCmd.Flags().BoolVar(&cacheDryRun, "dry-run", false, "Show which jars would be removed without removing them")
+}
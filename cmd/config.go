@@ -6,17 +6,31 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/properties"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/backup"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/rcon"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/supervisor"
 )
 
 var (
 	defaultMemory   string
 	defaultJavaArgs string
+	rconHost        string
 	rconPort        int
 	rconPassword    string
+	keepLast        int
+	keepDaily       int
+	keepWeekly      int
+	maxBackupSize   string
+	autoRestart     bool
+	maxRestarts     int
+	restartWindow   string
+	restartBackoff  string
+	maxBackoff      string
 )
 
 // configCmd represents the config command
@@ -24,13 +38,17 @@ var configCmd = &cobra.Command{
 	Use:   "config [server-name] [config-type]",
 	Short: "Configure server settings",
 	Long: `Configure server settings such as startup script, server properties, or operator list.
-Config types: start (startup script), properties (server.properties), ops (ops.json), rcon (RCON settings)
+Config types: start (startup script), properties (server.properties), ops (ops.json), rcon (RCON settings),
+retention (backup retention policy), restart (automatic restarts after a crash)
 
 Example:
   mcsrvr config paper123 start
   mcsrvr config paper123 properties
   mcsrvr config paper123 ops
   mcsrvr config paper123 rcon --port 25575 --password mypassword
+  mcsrvr config paper123 retention --keep-last 5 --keep-daily 7 --keep-weekly 4 --max-size 50G
+  mcsrvr config paper123 restart --max-restarts 5 --restart-window 10m --backoff 5s --max-backoff 5m
+  mcsrvr config paper123 restart --auto-restart=false
   mcsrvr config --default-memory 4G
   mcsrvr config --default-java-args "-XX:+UseG1GC -XX:+ParallelRefProcEnabled"`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -75,11 +93,23 @@ Example:
 			configureOps(serverConfig)
 		case "rcon":
 			// Configure RCON settings
-			if err := configureRcon(serverConfig, rconPort, rconPassword); err != nil {
+			if err := configureRcon(serverConfig, rconHost, rconPort, rconPassword); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: Failed to configure RCON: %v\n", err)
 				os.Exit(1)
 			}
 			fmt.Println("RCON configuration updated successfully")
+		case "retention":
+			// Configure the backup retention policy
+			if err := configureRetention(cmd, serverConfig); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to configure retention policy: %v\n", err)
+				os.Exit(1)
+			}
+		case "restart":
+			// Configure automatic restarts after a crash
+			if err := configureRestart(cmd, serverConfig); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to configure restart policy: %v\n", err)
+				os.Exit(1)
+			}
 		default:
 			fmt.Fprintf(os.Stderr, "Error: Unknown config type: %s\n", configType)
 			cmd.Help()
@@ -195,42 +225,142 @@ func configureOps(serverConfig config.ServerConfig) {
 	fmt.Println("ops.json updated successfully")
 }
 
-// configureRcon configures RCON settings in server.properties
-func configureRcon(serverConfig config.ServerConfig, port int, password string) error {
+// configureRcon configures the RCON settings of a server in its configuration and server.properties.
+// A zero port, empty host or empty password keeps the current value.
+func configureRcon(serverConfig config.ServerConfig, host string, port int, password string) error {
+	// Start from the settings the server currently uses
+	rconConfig, err := rcon.GetRCONConfig(serverConfig.Name)
+	if err != nil {
+		return err
+	}
+	if host != "" {
+		rconConfig.Host = host
+	}
+	if port != 0 {
+		rconConfig.Port = port
+	}
+	if password != "" {
+		rconConfig.Password = password
+	}
+	port = rconConfig.Port
+	password = rconConfig.Password
+
 	// Determine the server.properties path
 	propertiesPath := filepath.Join(serverConfig.Path, "server.properties")
 
-	// Check if the server.properties file exists
-	if _, err := os.Stat(propertiesPath); os.IsNotExist(err) {
+	// Read the server.properties file
+	props, err := properties.Load(propertiesPath)
+	if os.IsNotExist(err) {
 		return fmt.Errorf("server.properties file does not exist: %s", propertiesPath)
 	}
-
-	// Read the server.properties file
-	content, err := os.ReadFile(propertiesPath)
 	if err != nil {
 		return fmt.Errorf("failed to read server.properties: %w", err)
 	}
 
-	// Update the RCON settings
-	// This is a simple implementation that doesn't handle all cases
-	// In a real implementation, we would use a proper properties parser
-	lines := make([]string, 0)
-	for _, line := range strings.Split(string(content), "\n") {
-		if strings.HasPrefix(line, "enable-rcon=") {
-			line = "enable-rcon=true"
-		} else if strings.HasPrefix(line, "rcon.port=") {
-			line = fmt.Sprintf("rcon.port=%d", port)
-		} else if strings.HasPrefix(line, "rcon.password=") {
-			line = fmt.Sprintf("rcon.password=%s", password)
-		}
-		lines = append(lines, line)
-	}
+	// Update the RCON settings, keeping the rest of the file as it is
+	props.SetBool("enable-rcon", true)
+	props.SetInt("rcon.port", port)
+	props.Set("rcon.password", password)
 
 	// Write the updated server.properties file
-	if err := os.WriteFile(propertiesPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+	if err := props.Save(propertiesPath); err != nil {
 		return fmt.Errorf("failed to write server.properties: %w", err)
 	}
 
+	// Store the settings so mcsrvr connects to the right port with the right password
+	err = config.ModifyServer(serverConfig.Name, func(server *config.ServerConfig) error {
+		server.RCON = rconConfig
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update server configuration: %w", err)
+	}
+
+	fmt.Printf("RCON for server '%s' now uses %s:%d\n", serverConfig.Name, rconConfig.Host, rconConfig.Port)
+	if _, running := process.GetActiveServer(serverConfig.Name); running {
+		fmt.Println("Restart the server for the new RCON settings to take effect")
+	}
+
+	return nil
+}
+
+// configureRetention updates the backup retention policy of a server.
+// Only the flags that were given are changed, 0 disables a rule.
+func configureRetention(cmd *cobra.Command, serverConfig config.ServerConfig) error {
+	if cmd.Flags().Changed("max-size") {
+		if _, err := backup.ParseSize(maxBackupSize); err != nil {
+			return err
+		}
+	}
+
+	var retention config.Retention
+	err := config.ModifyServer(serverConfig.Name, func(server *config.ServerConfig) error {
+		if cmd.Flags().Changed("keep-last") {
+			server.Retention.KeepLast = keepLast
+		}
+		if cmd.Flags().Changed("keep-daily") {
+			server.Retention.KeepDaily = keepDaily
+		}
+		if cmd.Flags().Changed("keep-weekly") {
+			server.Retention.KeepWeekly = keepWeekly
+		}
+		if cmd.Flags().Changed("max-size") {
+			server.Retention.MaxTotalSize = maxBackupSize
+		}
+		retention = server.Retention
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	maxSize := retention.MaxTotalSize
+	if maxSize == "" {
+		maxSize = "unlimited"
+	}
+	fmt.Printf("Retention policy for server '%s': keep last %d, daily %d, weekly %d, max size %s\n",
+		serverConfig.Name, retention.KeepLast, retention.KeepDaily, retention.KeepWeekly, maxSize)
+
+	return nil
+}
+
+// configureRestart updates the policy for restarting a server after a crash.
+// Only the flags that were given are changed.
+func configureRestart(cmd *cobra.Command, serverConfig config.ServerConfig) error {
+	var policy supervisor.Policy
+	err := config.ModifyServer(serverConfig.Name, func(server *config.ServerConfig) error {
+		if cmd.Flags().Changed("auto-restart") {
+			server.Restart.Disabled = !autoRestart
+		}
+		if cmd.Flags().Changed("max-restarts") {
+			server.Restart.MaxRestarts = maxRestarts
+		}
+		if cmd.Flags().Changed("restart-window") {
+			server.Restart.Window = restartWindow
+		}
+		if cmd.Flags().Changed("backoff") {
+			server.Restart.Backoff = restartBackoff
+		}
+		if cmd.Flags().Changed("max-backoff") {
+			server.Restart.MaxBackoff = maxBackoff
+		}
+
+		// Validate the policy before saving it
+		var err error
+		policy, err = supervisor.ParsePolicy(server.Restart)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !policy.Enabled {
+		fmt.Printf("Automatic restarts are disabled for server '%s'\n", serverConfig.Name)
+		return nil
+	}
+	fmt.Printf("Restart policy for server '%s': at most %d restarts within %s, backoff %s up to %s\n",
+		serverConfig.Name, policy.MaxRestarts, policy.Window, policy.Backoff, policy.MaxBackoff)
+
 	return nil
 }
 
@@ -240,6 +370,16 @@ func init() {
 	// Define flags for the config command
 	configCmd.Flags().StringVar(&defaultMemory, "default-memory", "", "Default memory allocation for new servers")
 	configCmd.Flags().StringVar(&defaultJavaArgs, "default-java-args", "", "Default Java arguments for new servers")
-	configCmd.Flags().IntVar(&rconPort, "port", 25575, "RCON port")
-	configCmd.Flags().StringVar(&rconPassword, "password", "", "RCON password")
+	configCmd.Flags().StringVar(&rconHost, "host", "", "RCON host (default: keep current)")
+	configCmd.Flags().IntVar(&rconPort, "port", 0, "RCON port (default: keep current)")
+	configCmd.Flags().StringVar(&rconPassword, "password", "", "RCON password (default: keep current)")
+	configCmd.Flags().IntVar(&keepLast, "keep-last", 0, "Keep the newest N backups (for retention config-type)")
+	configCmd.Flags().IntVar(&keepDaily, "keep-daily", 0, "Keep one backup for each of the last N days (for retention config-type)")
+	configCmd.Flags().IntVar(&keepWeekly, "keep-weekly", 0, "Keep one backup for each of the last N weeks (for retention config-type)")
+	configCmd.Flags().StringVar(&maxBackupSize, "max-size", "", "Maximum total size of a server's backups, e.g. 50G (for retention config-type)")
+	configCmd.Flags().BoolVar(&autoRestart, "auto-restart", true, "Restart the server after a crash (for restart config-type)")
+	configCmd.Flags().IntVar(&maxRestarts, "max-restarts", 0, "Maximum number of restarts within the restart window (for restart config-type)")
+	configCmd.Flags().StringVar(&restartWindow, "restart-window", "", "Window in which restarts are counted, e.g. 10m (for restart config-type)")
+	configCmd.Flags().StringVar(&restartBackoff, "backoff", "", "Delay before the first restart, doubled after each crash, e.g. 5s (for restart config-type)")
+	configCmd.Flags().StringVar(&maxBackoff, "max-backoff", "", "Maximum delay between restarts, e.g. 5m (for restart config-type)")
 }
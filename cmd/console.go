@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -13,6 +15,7 @@ import (
 	"github.com/0v3rr1de0/mcsrvr/pkg/config"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/supervisor"
 )
 
 // consoleCmd represents the console command
@@ -20,7 +23,12 @@ var consoleCmd = &cobra.Command{
 	Use:   "console [server-name]",
 	Short: "Access the console of a Minecraft server",
 	Long: `Access the console of a Minecraft server by name.
-You can exit the console by typing 'exit'.
+The console is attached to the server's standard input and output, so it works
+without RCON and shows everything the server prints. Recent output is replayed
+when you attach. Type 'exit' or press Ctrl+C to detach; the server keeps running
+and you can attach again at any time.
+Servers started by older versions of mcsrvr fall back to following latest.log
+and sending commands over RCON.
 
 Example:
   mcsrvr console paper123`,
@@ -36,11 +44,18 @@ Example:
 		}
 
 		// Check if the server is running.
-		if _, exists := process.ActiveServers[serverName]; !exists {
+		proc, exists := process.GetActiveServer(serverName)
+		if !exists || !proc.Running {
 			fmt.Fprintf(os.Stderr, "Error: Server '%s' is not running. Start it first with 'mcsrvr start %s'\n", serverName, serverName)
 			os.Exit(1)
 		}
 
+		// Attach to the console of the supervisor if it has one
+		if proc.Supervised() && supervisor.ConsoleAvailable(serverName) {
+			attachConsole(serverName)
+			return
+		}
+
 		// Determine the appropriate key combination based on OS
 		exitKey := "Ctrl+C"
 		if runtime.GOOS == "darwin" {
@@ -136,6 +151,35 @@ Example:
 	},
 }
 
+// attachConsole attaches the terminal to the console socket of a supervised server until
+// the user detaches
+func attachConsole(serverName string) {
+	// Detach on Ctrl+C instead of exiting, so the terminal is left tidy
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+
+	detach := make(chan struct{})
+	go func() {
+		<-sigChan
+		close(detach)
+	}()
+
+	fmt.Printf("Attached to console of server '%s'.\n", serverName)
+	fmt.Println("Type 'exit' or press Ctrl+C to detach. The server keeps running.")
+
+	err := supervisor.Attach(serverName, os.Stdin, os.Stdout, detach)
+	if errors.Is(err, supervisor.ErrConsoleClosed) {
+		fmt.Printf("\nServer '%s' stopped, detached from console\n", serverName)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\nDetached from console")
+}
+
 func init() {
 	rootCmd.AddCommand(consoleCmd)
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/supervisor"
+)
+
+var crashesLimit int
+
+// crashesCmd represents the crashes command
+var crashesCmd = &cobra.Command{
+	Use:   "crashes [server-name]",
+	Short: "Show unexpected server exits",
+	Long: `Show the servers that exited unexpectedly, newest last, with their exit
+status, the crash report written to crash-reports/ and whether the server
+was restarted.
+Configure automatic restarts with 'mcsrvr config [server-name] restart'.
+
+Example:
+  mcsrvr crashes
+  mcsrvr crashes paper123 --lines 50`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var serverName string
+		if len(args) > 0 {
+			serverName = args[0]
+		}
+
+		crashes, err := supervisor.LoadCrashes(serverName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(crashes) == 0 {
+			fmt.Println("No crashes recorded.")
+			return
+		}
+
+		// Only show the most recent crashes
+		if crashesLimit > 0 && len(crashes) > crashesLimit {
+			crashes = crashes[len(crashes)-crashesLimit:]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "EXITED\tSERVER\tUPTIME\tSTATUS\tRESTARTED\tCRASH REPORT")
+		for _, crash := range crashes {
+			restarted := "no"
+			if crash.Restarted {
+				restarted = "yes"
+			}
+			report := crash.CrashReport
+			if report == "" {
+				report = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				crash.ExitedAt.Format(time.RFC1123), crash.Server,
+				crash.ExitedAt.Sub(crash.StartedAt).Round(time.Second), crash.Status, restarted, report)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(crashesCmd)
+
+	// Define flags for the crashes command
+	crashesCmd.Flags().IntVarP(&crashesLimit, "lines", "n", 20, "Number of crashes to show")
+}
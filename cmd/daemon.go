@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/scheduler"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run scheduled tasks in the foreground",
+	Long: `Run the scheduler daemon, which executes the scheduled tasks of all servers
+(backups, restarts and console commands) without relying on the system crontab.
+The daemon runs in the foreground until it is interrupted, so run it under
+a service manager such as systemd, or with nohup.
+Add tasks with 'mcsrvr schedule add'.
+
+Example:
+  mcsrvr daemon`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Stop the daemon on Ctrl+C or SIGTERM
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+		stop := make(chan struct{})
+		go func() {
+			<-sigChan
+			close(stop)
+		}()
+
+		if err := scheduler.Run(stop); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
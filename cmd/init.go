@@ -4,19 +4,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/spf13/cobra"
 	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server"
+	"github.com/spf13/cobra"
 )
 
 var (
-	serverName         string
-	serverType         string
-	serverVersion      string
-	serverMemory       string
-	serverJavaArgs     string
+	serverName          string
+	serverType          string
+	serverVersion       string
+	serverMemory        string
+	serverJavaArgs      string
+	serverBuild         string
 	fabricLoaderVersion string
+	quiltLoaderVersion  string
+	offlineInit         bool
+	serverJavaPath      string
 )
 
 // initCmd represents the init command
@@ -25,11 +31,21 @@ var initCmd = &cobra.Command{
 	Short: "Initialize a new Minecraft server",
 	Long: `Initialize a new Minecraft server at the specified path.
 If no path is provided, the current directory will be used.
+Server jars are kept in a download cache shared by all servers. With --offline
+no network requests are made and the jar must already be in the cache.
+Forge, NeoForge and Quilt servers are set up by running their installer with Java,
+which downloads the server libraries and therefore needs the network even
+with --offline.
 
 Example:
   mcsrvr init . -n paper123 --type papermc -v 1.21.4
   mcsrvr init D:/serverfolder -n vanilla123 --type vanilla -v 1.21.4
-  mcsrvr init D:/serverfolder -n fabric123 --type fabric -v 1.21.4 --fabric-loader 0.16.10`,
+  mcsrvr init D:/serverfolder -n fabric123 --type fabric -v 1.21.4 --fabric-loader 0.16.10
+  mcsrvr init D:/serverfolder -n quilt123 --type quilt -v 1.21.4 --quilt-loader 0.28.0
+  mcsrvr init D:/serverfolder -n paper789 --type papermc -v 1.21.4 --build 232
+  mcsrvr init D:/serverfolder -n forge123 --type forge -v 1.21.4 --build 54.0.16
+  mcsrvr init D:/serverfolder -n neo123 --type neoforge -v 1.21.4 --java /usr/lib/jvm/java-21/bin/java
+  mcsrvr init D:/serverfolder -n paper456 --type papermc -v 1.21.4 --offline`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		// Determine the server path
@@ -39,15 +55,8 @@ Example:
 		}
 
 		// Validate server type
-		validTypes := map[string]bool{
-			"papermc": true,
-			"vanilla": true,
-			"fabric":  true,
-			// Add other server types as they are implemented
-		}
-
-		if !validTypes[serverType] {
-			fmt.Fprintf(os.Stderr, "Error: Invalid server type '%s'. Supported types: papermc, vanilla, fabric\n", serverType)
+		if _, err := downloader.GetProvider(serverType); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
@@ -79,19 +88,24 @@ Example:
 			}
 		}
 
+		// Only use cached jars in offline mode
+		downloader.Offline = offlineInit
+
 		fmt.Printf("Initializing %s server '%s' at %s with version %s\n", serverType, serverName, serverPath, serverVersion)
 		fmt.Printf("Memory: %s, Java Args: %s\n", serverMemory, serverJavaArgs)
 
-		// Initialize the server
-		var initErr error
-		if serverType == "fabric" {
-			// For Fabric servers, pass the loader version
-			initErr = server.InitializeFabricServer(serverPath, serverName, serverVersion, fabricLoaderVersion, serverMemory, serverJavaArgs)
-		} else {
-			// For other server types
-			initErr = server.InitializeServer(serverPath, serverName, serverType, serverVersion, serverMemory, serverJavaArgs)
+		// Fabric builds are loader versions, which can also be given with --fabric-loader
+		if serverType == downloader.ProviderFabric && serverBuild == "" {
+			serverBuild = fabricLoaderVersion
+		}
+
+		// Quilt builds are loader versions too, which can also be given with --quilt-loader
+		if serverType == downloader.ProviderQuilt && serverBuild == "" {
+			serverBuild = quiltLoaderVersion
 		}
-		
+
+		// Initialize the server
+		initErr := server.InitializeServer(serverPath, serverName, serverType, serverVersion, serverBuild, serverMemory, serverJavaPath, serverJavaArgs)
 		if initErr != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to initialize server: %v\n", initErr)
 			os.Exit(1)
@@ -104,16 +118,17 @@ func init() {
 
 	// Define flags for the init command
 	initCmd.Flags().StringVarP(&serverName, "name", "n", "", "Name of the server (required)")
-	initCmd.Flags().StringVar(&serverType, "type", "", "Server type (papermc, vanilla, fabric, etc.) (required)")
+	initCmd.Flags().StringVar(&serverType, "type", "", fmt.Sprintf("Server type (%s) (required)", strings.Join(downloader.ProviderNames(), ", ")))
 	initCmd.Flags().StringVarP(&serverVersion, "version", "v", "latest", "Server version")
 	initCmd.Flags().StringVarP(&serverMemory, "memory", "m", "2G", "Memory allocation for the server (e.g., 2G, 4G)")
 	initCmd.Flags().StringVar(&serverJavaArgs, "java-args", "", "Additional Java arguments")
-	initCmd.Flags().StringVar(&fabricLoaderVersion, "fabric-loader", "0.16.10", "Fabric loader version (only for fabric server type)")
+	initCmd.Flags().StringVar(&serverJavaPath, "java", "", "Java executable used to run installers and the server (default: java on the PATH)")
+	initCmd.Flags().StringVar(&serverBuild, "build", "", "Build of the server software, e.g. a PaperMC build number or Forge version (default: latest)")
+	initCmd.Flags().StringVar(&fabricLoaderVersion, "fabric-loader", "", "Fabric loader version (only for fabric server type) (default: newest stable loader)")
+	initCmd.Flags().StringVar(&quiltLoaderVersion, "quilt-loader", "", "Quilt loader version (only for quilt server type) (default: newest stable loader)")
+	initCmd.Flags().BoolVar(&offlineInit, "offline", false, "Use only server jars from the download cache")
 
 	// Mark required flags
 	initCmd.MarkFlagRequired("name")
 	initCmd.MarkFlagRequired("type")
-	
-	// Initialize the configuration
-	config.Initialize()
 }
@@ -48,8 +48,13 @@ Example:
 		for _, srv := range servers {
 			// Determine server status
 			status := "Offline"
-			if proc, exists := process.ActiveServers[srv.Name]; exists && proc.Running {
-				status = "Online"
+			if proc, exists := process.GetActiveServer(srv.Name); exists {
+				if proc.Running {
+					status = "Online"
+				} else {
+					// The supervisor is waiting to restart the server after a crash
+					status = "Restarting"
+				}
 			}
 			
 			// Skip if filtering by status
@@ -68,7 +73,7 @@ Example:
 
 			// Get PID if server is running
 			pid := "-"
-			if proc, exists := process.ActiveServers[srv.Name]; exists && proc.Running {
+			if proc, exists := process.GetActiveServer(srv.Name); exists && proc.Running {
 				pid = fmt.Sprintf("%d", proc.PID)
 			}
 
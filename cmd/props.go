@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server"
+)
+
+var (
+	propsLive    bool
+	propsForce   bool
+	propsAgainst string
+)
+
+// propsCmd represents the props command
+var propsCmd = &cobra.Command{
+	Use:   "props [server-name] [get|set|unset|diff] [key[=value]...]",
+	Short: "Read and change server.properties without an editor",
+	Long: `Read and change the server.properties of a server from scripts.
+Edits keep the order, comments and formatting of the rest of the file.
+
+get prints the given keys, or every key if none are given. A single key is
+printed as its bare value.
+set sets one or more key=value pairs. Values of the known vanilla keys are
+checked against their type and range; --force writes them unchecked. With
+--live, values the server can change while running (difficulty, gamemode,
+player-idle-timeout and white-list) are also applied through its console.
+unset removes keys, so the server falls back to their defaults.
+diff lists the keys whose values differ from the server given with --against.
+
+The RCON keys are managed with 'mcsrvr config <server-name> rcon' instead.
+
+Example:
+  mcsrvr props paper123 get
+  mcsrvr props paper123 get max-players
+  mcsrvr props paper123 set max-players=50 view-distance=12
+  mcsrvr props paper123 set difficulty=hard --live
+  mcsrvr props paper123 unset resource-pack
+  mcsrvr props paper123 diff --against paper456`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverName := args[0]
+		action := args[1]
+		keys := args[2:]
+
+		switch action {
+		case "get":
+			getProperties(serverName, keys)
+		case "set":
+			if len(keys) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: At least one key=value pair is required\n")
+				os.Exit(1)
+			}
+			var changes []server.PropertyChange
+			for _, arg := range keys {
+				key, value, found := strings.Cut(arg, "=")
+				if !found || key == "" {
+					fmt.Fprintf(os.Stderr, "Error: Invalid property %q, expected key=value\n", arg)
+					os.Exit(1)
+				}
+				changes = append(changes, server.PropertyChange{Key: key, Value: value})
+			}
+			if err := server.SetProperties(serverName, changes, propsLive, propsForce); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to set properties: %v\n", err)
+				os.Exit(1)
+			}
+		case "unset":
+			if len(keys) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: At least one key is required\n")
+				os.Exit(1)
+			}
+			if err := server.UnsetProperties(serverName, keys); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to unset properties: %v\n", err)
+				os.Exit(1)
+			}
+		case "diff":
+			if propsAgainst == "" {
+				fmt.Fprintf(os.Stderr, "Error: --against is required for diff\n")
+				os.Exit(1)
+			}
+			diffProperties(serverName, propsAgainst)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown action: %s\n", action)
+			cmd.Help()
+			os.Exit(1)
+		}
+	},
+}
+
+// getProperties prints keys of a server's server.properties, or all of them if none are given
+func getProperties(serverName string, keys []string) {
+	props, err := server.LoadProperties(serverName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// A single key is printed as its bare value, so scripts can use it directly
+	if len(keys) == 1 {
+		value, ok := props.Get(keys[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s is not set\n", keys[0])
+			os.Exit(1)
+		}
+		fmt.Println(value)
+		return
+	}
+
+	if len(keys) == 0 {
+		keys = props.Keys()
+	}
+	missing := false
+	for _, key := range keys {
+		value, ok := props.Get(key)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s is not set\n", key)
+			missing = true
+			continue
+		}
+		fmt.Printf("%s=%s\n", key, value)
+	}
+	if missing {
+		os.Exit(1)
+	}
+}
+
+// diffProperties prints the keys whose values differ between the server.properties of two servers
+func diffProperties(serverName, otherName string) {
+	differences, err := server.DiffProperties(serverName, otherName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to compare properties: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(differences) == 0 {
+		fmt.Printf("The server.properties of '%s' and '%s' are the same.\n", serverName, otherName)
+		return
+	}
+
+	// Print the differences
+	unset := func(value string, ok bool) string {
+		if !ok {
+			return "(unset)"
+		}
+		if value == "" {
+			return `""`
+		}
+		return value
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "KEY\t%s\t%s\n", serverName, otherName)
+	for _, d := range differences {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Key, unset(d.Value, d.HasValue), unset(d.Other, d.HasOther))
+	}
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(propsCmd)
+
+	// Define flags for the props command
+	propsCmd.Flags().BoolVar(&propsLive, "live", false, "Apply values that can be changed while the server runs through its console")
+	propsCmd.Flags().BoolVar(&propsForce, "force", false, "Write values without checking them against the vanilla keys")
+	propsCmd.Flags().StringVar(&propsAgainst, "against", "", "Server to compare with for diff")
+}
@@ -3,12 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/0v3rr1de0/mcsrvr/pkg/config"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server"
-	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
 )
 
 // restartCmd represents the restart command
@@ -30,34 +28,9 @@ Example:
 			os.Exit(1)
 		}
 
-		// Check if the server is running
-		proc, exists := process.ActiveServers[serverName]
-		if !exists || !proc.Running {
-			fmt.Fprintf(os.Stderr, "Error: Server '%s' is not running\n", serverName)
-			os.Exit(1)
-		}
-
-		// Stop the server
-		fmt.Printf("Stopping server '%s'...\n", serverName)
-		if err := server.StopServer(serverName); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to stop server: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Wait a moment for the server to fully stop
-		fmt.Println("Waiting for server to stop...")
-		time.Sleep(5 * time.Second)
-
-		// Verify that the server is fully stopped
-		if proc, exists := process.ActiveServers[serverName]; exists && proc.Running {
-			fmt.Fprintf(os.Stderr, "Error: Server '%s' is still running after stop command\n", serverName)
-			os.Exit(1)
-		}
-
-		// Start the server
-		fmt.Printf("Starting server '%s'...\n", serverName)
-		if err := server.StartServer(serverName); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to start server: %v\n", err)
+		// Restart the server
+		if err := server.RestartServer(serverName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
@@ -1,7 +1,17 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
+)
+
+var (
+	mcsrvrHome   string
+	systemLayout bool
 )
 
 var rootCmd = &cobra.Command{
@@ -11,7 +21,23 @@ var rootCmd = &cobra.Command{
 It supports various server types like vanilla, PaperMC, Spigot, Bukkit, Velocity, 
 Forge, Fabric, BungeeCord, and Cuberite.
 
-You can initialize, start, stop, backup, and manage your Minecraft servers with simple commands.`,
+You can initialize, start, stop, backup, and manage your Minecraft servers with simple commands.
+
+mcsrvr keeps its configuration and state in ~/.mcsrvr. New installations on
+Linux use the XDG config, state and data directories instead. Use --home or
+MCSRVR_HOME to keep everything in another directory, and --system or
+MCSRVR_SYSTEM=1 to use /etc/mcsrvr and /var/lib/mcsrvr for servers run by a
+service account.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Resolve and create the directories of mcsrvr before any command uses them
+		if err := config.Initialize(config.Options{Home: mcsrvrHome, System: systemLayout}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to initialize mcsrvr directories: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Load the active servers and drop those that are no longer running
+		process.RefreshServerStatus()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -25,7 +51,8 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.mcsrvr.yaml)")
+	rootCmd.PersistentFlags().StringVar(&mcsrvrHome, "home", "", "Directory for all files of mcsrvr (default: $MCSRVR_HOME, ~/.mcsrvr or the XDG directories)")
+	rootCmd.PersistentFlags().BoolVar(&systemLayout, "system", false, "Use the system-wide directories /etc/mcsrvr and /var/lib/mcsrvr")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/scheduler"
+)
+
+var (
+	scheduleCron     string
+	scheduleAction   string
+	scheduleCommand  string
+	scheduleWarnings []string
+	historyLimit     int
+)
+
+// scheduleCmd represents the schedule command
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage scheduled tasks",
+	Long: `Manage recurring tasks that are run by 'mcsrvr daemon'.
+Supported actions: backup, prune, restart and command.
+
+Example:
+  mcsrvr schedule add paper123 nightly-backup --cron "0 3 * * *" --action backup
+  mcsrvr schedule add paper123 restart --cron "0 */6 * * *" --action restart --warn 5m,1m,10s
+  mcsrvr schedule add paper123 announce --cron "@hourly" --action command --command "say Vote for us!"
+  mcsrvr schedule list
+  mcsrvr schedule history paper123`,
+}
+
+// scheduleAddCmd represents the schedule add command
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add [server-name] [schedule-name]",
+	Short: "Add or replace a scheduled task",
+	Long: `Add a scheduled task to a server, replacing any task with the same name.
+The cron expression has five fields (minute hour day month weekday) and also
+accepts @hourly, @daily, @weekly, @monthly and @yearly.
+For restarts, --warn sets the in-game countdown announcements. The countdown
+starts at the scheduled time and the server restarts when it reaches zero.
+
+Example:
+  mcsrvr schedule add paper123 nightly-backup --cron "0 3 * * *" --action backup
+  mcsrvr schedule add paper123 restart --cron "0 */6 * * *" --action restart --warn 5m,1m,10s`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverName := args[0]
+
+		// Get the server configuration
+		schedule := config.Schedule{
+			Name:     args[1],
+			Cron:     scheduleCron,
+			Action:   scheduleAction,
+			Command:  scheduleCommand,
+			Warnings: scheduleWarnings,
+		}
+		if err := scheduler.Validate(schedule); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid schedule: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Replace an existing schedule with the same name, or add a new one
+		err := config.ModifyServer(serverName, func(serverConfig *config.ServerConfig) error {
+			replaced := false
+			for i, existing := range serverConfig.Schedules {
+				if existing.Name == schedule.Name {
+					serverConfig.Schedules[i] = schedule
+					replaced = true
+				}
+			}
+			if !replaced {
+				serverConfig.Schedules = append(serverConfig.Schedules, schedule)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to save schedule: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Schedule '%s' saved for server '%s'\n", schedule.Name, serverName)
+		fmt.Println("Scheduled tasks only run while 'mcsrvr daemon' is running")
+	},
+}
+
+// scheduleRemoveCmd represents the schedule remove command
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove [server-name] [schedule-name]",
+	Short: "Remove a scheduled task",
+	Long: `Remove a scheduled task from a server.
+
+Example:
+  mcsrvr schedule remove paper123 nightly-backup`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverName := args[0]
+		scheduleName := args[1]
+
+		err := config.ModifyServer(serverName, func(serverConfig *config.ServerConfig) error {
+			schedules := make([]config.Schedule, 0, len(serverConfig.Schedules))
+			for _, schedule := range serverConfig.Schedules {
+				if schedule.Name != scheduleName {
+					schedules = append(schedules, schedule)
+				}
+			}
+			if len(schedules) == len(serverConfig.Schedules) {
+				return fmt.Errorf("server '%s' has no schedule named '%s'", serverName, scheduleName)
+			}
+			serverConfig.Schedules = schedules
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to remove schedule: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Schedule '%s' removed from server '%s'\n", scheduleName, serverName)
+	},
+}
+
+// scheduleListCmd represents the schedule list command
+var scheduleListCmd = &cobra.Command{
+	Use:   "list [server-name]",
+	Short: "List scheduled tasks",
+	Long: `List the scheduled tasks of all servers, or of a single server.
+
+Example:
+  mcsrvr schedule list
+  mcsrvr schedule list paper123`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get the servers to list
+		var servers []config.ServerConfig
+		if len(args) > 0 {
+			serverConfig, err := config.GetServer(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			servers = append(servers, serverConfig)
+		} else {
+			var err error
+			servers, err = config.ListServers()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to list servers: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVER\tNAME\tCRON\tACTION\tDETAILS\tNEXT RUN")
+
+		count := 0
+		for _, srv := range servers {
+			for _, schedule := range srv.Schedules {
+				nextRun := "-"
+				if cron, err := scheduler.ParseCron(schedule.Cron); err == nil {
+					if next := cron.Next(time.Now()); !next.IsZero() {
+						nextRun = next.Format(time.RFC1123)
+					}
+				}
+
+				details := "-"
+				if schedule.Action == scheduler.ActionCommand {
+					details = schedule.Command
+				} else if len(schedule.Warnings) > 0 {
+					details = fmt.Sprintf("warnings: %v", schedule.Warnings)
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					srv.Name, schedule.Name, schedule.Cron, schedule.Action, details, nextRun)
+				count++
+			}
+		}
+
+		if count == 0 {
+			fmt.Println("No scheduled tasks found. Use 'mcsrvr schedule add' to create one.")
+			return
+		}
+		w.Flush()
+	},
+}
+
+// scheduleHistoryCmd represents the schedule history command
+var scheduleHistoryCmd = &cobra.Command{
+	Use:   "history [server-name]",
+	Short: "Show the results of past scheduled runs",
+	Long: `Show the results of tasks run by the scheduler daemon, newest last.
+
+Example:
+  mcsrvr schedule history
+  mcsrvr schedule history paper123 --lines 50`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var serverName string
+		if len(args) > 0 {
+			serverName = args[0]
+		}
+
+		runs, err := scheduler.LoadHistory(serverName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(runs) == 0 {
+			fmt.Println("No scheduled runs recorded yet.")
+			return
+		}
+
+		// Only show the most recent runs
+		if historyLimit > 0 && len(runs) > historyLimit {
+			runs = runs[len(runs)-historyLimit:]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STARTED\tSERVER\tSCHEDULE\tACTION\tDURATION\tRESULT")
+		for _, run := range runs {
+			result := "OK"
+			if !run.Success {
+				result = "FAILED: " + run.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				run.StartedAt.Format(time.RFC1123), run.Server, run.Schedule, run.Action,
+				run.FinishedAt.Sub(run.StartedAt).Round(time.Second), result)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleHistoryCmd)
+
+	// Define flags for the schedule add command
+	scheduleAddCmd.Flags().StringVar(&scheduleCron, "cron", "", "Cron expression, e.g. \"0 3 * * *\" or @daily (required)")
+	scheduleAddCmd.Flags().StringVar(&scheduleAction, "action", "", "Action to run: backup, prune, restart or command (required)")
+	scheduleAddCmd.Flags().StringVar(&scheduleCommand, "command", "", "Console command to send (for the command action)")
+	scheduleAddCmd.Flags().StringSliceVar(&scheduleWarnings, "warn", nil, "Countdown announcements before a restart, e.g. 5m,1m,10s")
+	scheduleAddCmd.MarkFlagRequired("cron")
+	scheduleAddCmd.MarkFlagRequired("action")
+
+	// Define flags for the schedule history command
+	scheduleHistoryCmd.Flags().IntVarP(&historyLimit, "lines", "n", 20, "Number of runs to show")
+}
@@ -8,20 +8,27 @@ import (
 	"github.com/0v3rr1de0/mcsrvr/pkg/server"
 )
 
+var stopOptions = server.DefaultStopOptions()
+
 // stopCmd represents the stop command
 var stopCmd = &cobra.Command{
 	Use:   "stop [server-name]",
 	Short: "Stop a Minecraft server",
 	Long: `Stop a Minecraft server by name.
+With --warn, players are warned with an in-game countdown first. The server is then
+sent the stop command and given --timeout to save the world and exit, while its
+progress is shown. Only if it is still running after that is it sent SIGTERM, and
+only if it ignores that for --grace is it killed.
 
 Example:
-  mcsrvr stop paper123`,
+  mcsrvr stop paper123
+  mcsrvr stop paper123 --timeout 120s --warn 30s`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		serverName := args[0]
 
 		// Stop the server
-		if err := server.StopServer(serverName); err != nil {
+		if err := server.StopServer(serverName, stopOptions); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to stop server: %v\n", err)
 			os.Exit(1)
 		}
@@ -30,4 +37,9 @@ Example:
 
 func init() {
 	rootCmd.AddCommand(stopCmd)
+
+	// Define flags for the stop command
+	stopCmd.Flags().DurationVar(&stopOptions.Timeout, "timeout", server.DefaultStopTimeout, "How long to wait for the server to exit after the stop command")
+	stopCmd.Flags().DurationVar(&stopOptions.Warn, "warn", 0, "Warn players with an in-game countdown of this length before stopping, e.g. 30s")
+	stopCmd.Flags().DurationVar(&stopOptions.Grace, "grace", server.DefaultKillGracePeriod, "How long to wait after SIGTERM before killing the server")
 }
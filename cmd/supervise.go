@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/supervisor"
+)
+
+// superviseCmd represents the supervise command, which 'mcsrvr start' runs in the background
+var superviseCmd = &cobra.Command{
+	Use:    "supervise [server-name]",
+	Short:  "Run a Minecraft server and restart it when it crashes",
+	Hidden: true,
+	Long: `Run a Minecraft server in the foreground and restart it with exponential
+backoff when it exits unexpectedly. This command is started in the background
+by 'mcsrvr start' and is not meant to be run directly.
+
+Example:
+  mcsrvr supervise paper123`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverName := args[0]
+
+		// Run the server until it is stopped
+		if err := supervisor.Run(serverName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(superviseCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server"
+)
+
+var (
+	upgradeTo       string
+	upgradeBuild    string
+	upgradeRestart  bool
+	upgradeRollback bool
+)
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [server-name]",
+	Short: "Upgrade the server software of a Minecraft server in place",
+	Long: `Upgrade the server software of a Minecraft server in place.
+--to selects a version, 'latest' for the latest version, or 'latest-build' for
+the latest build of the version the server is already on. --build selects a
+specific build of the target version instead of the latest one.
+
+The server is backed up before the upgrade, then the new jar is installed and
+the startup script and server configuration are updated. The previous jar is
+kept, and --rollback switches back to it. A running server is only upgraded
+with --restart, which stops it for the upgrade and starts it again afterwards.
+
+Example:
+  mcsrvr upgrade paper123 --to latest-build
+  mcsrvr upgrade paper123 --to 1.21.4 --build 232
+  mcsrvr upgrade paper123 --to latest --restart
+  mcsrvr upgrade paper123 --rollback`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverName := args[0]
+
+		// Roll back to the previous installation
+		if upgradeRollback {
+			if upgradeTo != "" || upgradeBuild != "" {
+				fmt.Fprintf(os.Stderr, "Error: --rollback cannot be combined with --to or --build\n")
+				os.Exit(1)
+			}
+			if err := server.RollbackServer(serverName, upgradeRestart); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to roll back server: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if upgradeTo == "" {
+			fmt.Fprintf(os.Stderr, "Error: Either --to or --rollback is required\n")
+			os.Exit(1)
+		}
+
+		// Upgrade the server
+		if err := server.UpgradeServer(serverName, upgradeTo, upgradeBuild, resolveBackupPath(), upgradeRestart); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to upgrade server: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	// Define flags for the upgrade command
+	upgradeCmd.Flags().StringVar(&upgradeTo, "to", "", "Version to upgrade to, 'latest' or 'latest-build'")
+	upgradeCmd.Flags().StringVar(&upgradeBuild, "build", "", "Build of the target version (default: latest)")
+	upgradeCmd.Flags().BoolVar(&upgradeRestart, "restart", false, "Stop a running server for the upgrade and start it again afterwards")
+	upgradeCmd.Flags().BoolVar(&upgradeRollback, "rollback", false, "Switch back to the installation before the last upgrade")
+	upgradeCmd.Flags().StringVar(&backupPath, "path", "", "Path of the backup repository used for the backup before the upgrade")
+}
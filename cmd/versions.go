@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+)
+
+var (
+	versionsBuilds    string
+	versionsSnapshots bool
+	versionsJSON      bool
+)
+
+// versionEntry is a version or build as printed by the versions command
+type versionEntry struct {
+	ID       string `json:"id"`
+	Channel  string `json:"channel"`
+	Released string `json:"released,omitempty"`
+}
+
+// versionsCmd represents the versions command
+var versionsCmd = &cobra.Command{
+	Use:   "versions <type>",
+	Short: "List the versions and builds available for a server type",
+	Long: `List the versions available for a server type, newest first, with their
+release date and channel. By default only stable versions are shown; use
+--snapshots to include snapshots, pre-releases and old versions.
+With --builds, the builds of a version are listed instead, such as PaperMC
+builds or Fabric loader versions. 'latest' selects the newest stable version.
+
+Example:
+  mcsrvr versions papermc
+  mcsrvr versions vanilla --snapshots
+  mcsrvr versions papermc --builds 1.21.4
+  mcsrvr versions fabric --builds latest --json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		provider, err := downloader.GetProvider(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var entries []versionEntry
+		version := versionsBuilds
+		if versionsBuilds != "" {
+			version, err = resolveVersion(provider, versionsBuilds)
+			if err == nil {
+				entries, err = listBuilds(provider, version)
+			}
+		} else {
+			entries, err = listVersions(provider, versionsSnapshots)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if versionsJSON {
+			if entries == nil {
+				entries = []versionEntry{}
+			}
+			data, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to encode versions: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			return
+		}
+
+		if len(entries) == 0 {
+			if versionsBuilds != "" {
+				fmt.Printf("No builds found for %s %s.\n", provider.Description(), version)
+			} else {
+				fmt.Printf("No versions found for %s.\n", provider.Description())
+			}
+			return
+		}
+
+		// Print the versions or builds
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if versionsBuilds != "" {
+			fmt.Printf("Builds of %s %s:\n", provider.Description(), version)
+			fmt.Fprintln(w, "BUILD\tCHANNEL\tRELEASED")
+		} else {
+			fmt.Fprintln(w, "VERSION\tCHANNEL\tRELEASED")
+		}
+		for _, e := range entries {
+			released := e.Released
+			if released == "" {
+				released = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", e.ID, e.Channel, released)
+		}
+		w.Flush()
+	},
+}
+
+// listVersions lists the versions of a provider, leaving out snapshots and old versions
+// unless they are requested
+func listVersions(provider downloader.Provider, snapshots bool) ([]versionEntry, error) {
+	versions, err := provider.Versions()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []versionEntry
+	for _, v := range versions {
+		if !snapshots && v.Channel != downloader.ChannelStable {
+			continue
+		}
+		entries = append(entries, versionEntry{ID: v.ID, Channel: v.Channel, Released: formatReleaseTime(v.ReleaseTime)})
+	}
+	return entries, nil
+}
+
+// resolveVersion returns the newest stable version of a provider for "latest", and any
+// other version unchanged
+func resolveVersion(provider downloader.Provider, version string) (string, error) {
+	if version != downloader.VersionLatest {
+		return version, nil
+	}
+
+	versions, err := provider.Versions()
+	if err != nil {
+		return "", err
+	}
+	for _, v := range versions {
+		if v.Channel == downloader.ChannelStable {
+			return v.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no stable %s version found", provider.Description())
+}
+
+// listBuilds lists the builds of a version of a provider
+func listBuilds(provider downloader.Provider, version string) ([]versionEntry, error) {
+	builds, err := provider.Builds(version)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]versionEntry, 0, len(builds))
+	for _, b := range builds {
+		entries = append(entries, versionEntry{ID: b.ID, Channel: b.Channel, Released: formatReleaseTime(b.Time)})
+	}
+	return entries, nil
+}
+
+// formatReleaseTime formats the release date of a version or build, which not every
+// provider knows
+func formatReleaseTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02")
+}
+
+func init() {
+	rootCmd.AddCommand(versionsCmd)
+
+	// Define flags for the versions command
+	versionsCmd.Flags().StringVar(&versionsBuilds, "builds", "", "List the builds of this version instead of the versions")
+	versionsCmd.Flags().BoolVar(&versionsSnapshots, "snapshots", false, "Include snapshots, pre-releases and old versions")
+	versionsCmd.Flags().BoolVar(&versionsJSON, "json", false, "Print the versions or builds as JSON")
+}
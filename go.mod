@@ -2,9 +2,13 @@ module github.com/0v3rr1de0/mcsrvr
 
 go 1.23.4
 
+require (
+	github.com/jltobler/go-rcon v0.3.0
+	github.com/spf13/cobra v1.9.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/jltobler/go-rcon v0.3.0 // indirect
-	github.com/spf13/cobra v1.9.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 )
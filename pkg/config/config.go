@@ -1,23 +1,88 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/store"
 )
 
 // ServerConfig represents the configuration for a Minecraft server
 type ServerConfig struct {
-	Name        string    `json:"name"`
-	Type        string    `json:"type"`
-	Version     string    `json:"version"`
-	Path        string    `json:"path"`
-	Memory      string    `json:"memory"`
-	JavaArgs    string    `json:"javaArgs,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	LastStarted time.Time `json:"lastStarted,omitempty"`
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Version     string        `json:"version"`
+	Build       string        `json:"build,omitempty"`
+	Path        string        `json:"path"`
+	Memory      string        `json:"memory"`
+	JavaArgs    string        `json:"javaArgs,omitempty"`
+	Jar         string        `json:"jar,omitempty"`
+	ArgsFiles   []string      `json:"argsFiles,omitempty"`
+	Previous    *Installation `json:"previous,omitempty"`
+	JavaPath    string        `json:"javaPath,omitempty"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	LastStarted time.Time     `json:"lastStarted,omitempty"`
+	RCON        RCONConfig    `json:"rcon"`
+	Retention   Retention     `json:"retention"`
+	Restart     RestartPolicy `json:"restart"`
+	Schedules   []Schedule    `json:"schedules,omitempty"`
+	// Addons are the plugin and mod files installed by 'mcsrvr apply', relative to the server
+	// directory, so they can be removed again when they are dropped from the spec
+	Addons []string `json:"addons,omitempty"`
+}
+
+// Installation records a version of the server software installed in a server directory.
+// A server keeps its previous installation after an upgrade so it can be rolled back.
+type Installation struct {
+	Version   string   `json:"version"`
+	Build     string   `json:"build,omitempty"`
+	Jar       string   `json:"jar,omitempty"`
+	ArgsFiles []string `json:"argsFiles,omitempty"`
+}
+
+// RCONConfig represents the RCON connection settings of a server.
+// A zero port means the server was created before per-server RCON settings existed.
+type RCONConfig struct {
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// Retention represents the backup retention policy of a server.
+// Zero values disable a rule, and no backups are pruned if all rules are disabled.
+type Retention struct {
+	KeepLast     int    `json:"keepLast,omitempty"`
+	KeepDaily    int    `json:"keepDaily,omitempty"`
+	KeepWeekly   int    `json:"keepWeekly,omitempty"`
+	MaxTotalSize string `json:"maxTotalSize,omitempty"`
+}
+
+// RestartPolicy controls how a server that exits unexpectedly is restarted by its supervisor.
+// Zero values use the supervisor's defaults.
+type RestartPolicy struct {
+	// Disabled turns off automatic restarts, crashes are still recorded
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxRestarts is the number of restarts allowed within Window before the supervisor gives up
+	MaxRestarts int    `json:"maxRestarts,omitempty"`
+	Window      string `json:"window,omitempty"`
+	// Backoff is the delay before the first restart, doubled after each further crash up to MaxBackoff
+	Backoff    string `json:"backoff,omitempty"`
+	MaxBackoff string `json:"maxBackoff,omitempty"`
+}
+
+// Schedule represents a recurring task run by the mcsrvr daemon
+type Schedule struct {
+	Name string `json:"name"`
+	// Cron is a five-field cron expression or a macro such as @daily
+	Cron string `json:"cron"`
+	// Action is one of backup, prune, restart or command
+	Action string `json:"action"`
+	// Command is the console command sent by the command action
+	Command string `json:"command,omitempty"`
+	// Warnings are the countdown announcements made before a restart, e.g. ["5m", "1m", "10s"]
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // Config represents the global configuration for the mcsrvr tool
@@ -25,28 +90,45 @@ type Config struct {
 	Servers map[string]ServerConfig `json:"servers"`
 }
 
-// configDir is the directory where the configuration file is stored
-var configDir string
-
 // configFile is the path to the configuration file
 var configFile string
 
-// Initialize initializes the configuration
-func Initialize() error {
-	homeDir, err := os.UserHomeDir()
+// Initialize resolves the directories mcsrvr keeps its files in, creates them and creates
+// the configuration file if it doesn't exist. It must be called before anything else in
+// mcsrvr reads or writes its files.
+func Initialize(options Options) error {
+	resolved, err := ResolveLayout(options)
 	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %w", err)
+		return err
+	}
+	layout = resolved
+	configFile = filepath.Join(layout.Config, "config.json")
+
+	// Processes started by mcsrvr, such as supervisors, inherit the environment, so they use
+	// the same directories even though they run in another working directory
+	switch {
+	case options.Home != "" || (!options.System && os.Getenv(HomeEnv) != ""):
+		os.Setenv(HomeEnv, layout.Config)
+	case options.System:
+		os.Unsetenv(HomeEnv)
+		os.Setenv(SystemEnv, "1")
 	}
 
-	configDir = filepath.Join(homeDir, ".mcsrvr")
-	configFile = filepath.Join(configDir, "config.json")
+	// Create the directories if they don't exist
+	for _, dir := range []string{layout.Config, layout.State, layout.Data} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
 
-	// Create the config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	// Create the config file if it doesn't exist, under the lock so a concurrent
+	// mcsrvr process cannot replace a file another one just created
+	lock, err := store.Lock(configFile)
+	if err != nil {
+		return err
 	}
+	defer lock.Unlock()
 
-	// Create the config file if it doesn't exist
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		config := Config{
 			Servers: make(map[string]ServerConfig),
@@ -57,62 +139,71 @@ func Initialize() error {
 	return nil
 }
 
-// LoadConfig loads the configuration from the config file
+// LoadConfig loads the configuration from the config file. If the file is damaged, the
+// last good version is loaded from config.json.bak.
 func LoadConfig() (Config, error) {
 	var config Config
 
-	data, err := os.ReadFile(configFile)
-	if err != nil {
-		return config, fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	if err := json.Unmarshal(data, &config); err != nil {
+	if err := store.LoadJSON(configFile, &config); err != nil {
+		if os.IsNotExist(err) {
+			return config, fmt.Errorf("failed to read config file: %w", err)
+		}
 		return config, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	if config.Servers == nil {
+		config.Servers = make(map[string]ServerConfig)
+	}
 
 	return config, nil
 }
 
-// saveConfig saves the configuration to the config file
+// saveConfig saves the configuration to the config file. The caller must hold the lock
+// of the config file.
 func saveConfig(config Config) error {
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	if err := store.SaveJSON(configFile, config); err != nil {
+		return fmt.Errorf("failed to save config file: %w", err)
 	}
 
 	return nil
 }
 
-// AddServer adds a server to the configuration
-func AddServer(name, serverType, version, path, memory, javaArgs string) error {
-	config, err := LoadConfig()
+// modifyConfig loads the configuration, changes it and saves it while holding the lock
+// of the config file, so changes made by concurrent mcsrvr processes are not lost.
+// Nothing is saved if fn returns an error.
+func modifyConfig(fn func(config *Config) error) error {
+	lock, err := store.Lock(configFile)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 
-	// Check if a server with the same name already exists
-	if _, exists := config.Servers[name]; exists {
-		return fmt.Errorf("server with name '%s' already exists", name)
+	config, err := LoadConfig()
+	if err != nil {
+		return err
 	}
 
-	// Add the server to the configuration
-	config.Servers[name] = ServerConfig{
-		Name:      name,
-		Type:      serverType,
-		Version:   version,
-		Path:      path,
-		Memory:    memory,
-		JavaArgs:  javaArgs,
-		CreatedAt: time.Now(),
+	if err := fn(&config); err != nil {
+		return err
 	}
 
 	return saveConfig(config)
 }
 
+// AddServer adds a server to the configuration
+func AddServer(server ServerConfig) error {
+	return modifyConfig(func(config *Config) error {
+		// Check if a server with the same name already exists
+		if _, exists := config.Servers[server.Name]; exists {
+			return fmt.Errorf("server with name '%s' already exists", server.Name)
+		}
+
+		// Add the server to the configuration
+		server.CreatedAt = time.Now()
+		config.Servers[server.Name] = server
+		return nil
+	})
+}
+
 // GetServer gets a server from the configuration
 func GetServer(name string) (ServerConfig, error) {
 	config, err := LoadConfig()
@@ -143,34 +234,35 @@ func ListServers() ([]ServerConfig, error) {
 	return servers, nil
 }
 
-// UpdateServer updates a server in the configuration
-func UpdateServer(name string, updatedServer ServerConfig) error {
-	config, err := LoadConfig()
-	if err != nil {
-		return err
-	}
-
-	if _, exists := config.Servers[name]; !exists {
-		return fmt.Errorf("server with name '%s' does not exist", name)
-	}
+// ModifyServer changes a server in the configuration with fn. The server is read and
+// written under the lock of the config file, so fields changed by other mcsrvr processes
+// at the same time are kept. fn must not call other functions that change the configuration,
+// and nothing is saved if it returns an error.
+func ModifyServer(name string, fn func(server *ServerConfig) error) error {
+	return modifyConfig(func(config *Config) error {
+		server, exists := config.Servers[name]
+		if !exists {
+			return fmt.Errorf("server with name '%s' does not exist", name)
+		}
 
-	config.Servers[name] = updatedServer
-	return saveConfig(config)
+		if err := fn(&server); err != nil {
+			return err
+		}
+		config.Servers[name] = server
+		return nil
+	})
 }
 
 // DeleteServer deletes a server from the configuration
 func DeleteServer(name string) error {
-	config, err := LoadConfig()
-	if err != nil {
-		return err
-	}
-
-	if _, exists := config.Servers[name]; !exists {
-		return fmt.Errorf("server with name '%s' does not exist", name)
-	}
+	return modifyConfig(func(config *Config) error {
+		if _, exists := config.Servers[name]; !exists {
+			return fmt.Errorf("server with name '%s' does not exist", name)
+		}
 
-	delete(config.Servers, name)
-	return saveConfig(config)
+		delete(config.Servers, name)
+		return nil
+	})
 }
 
 // DefaultConfig represents the default configuration for new servers
@@ -182,19 +274,18 @@ type DefaultConfig struct {
 // UpdateDefaults updates the default configuration for new servers
 func UpdateDefaults(memory, javaArgs string) error {
 	// Create the defaults file path
-	defaultsFile := filepath.Join(configDir, "defaults.json")
+	defaultsFile := filepath.Join(layout.Config, "defaults.json")
+
+	lock, err := store.Lock(defaultsFile)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
 
 	// Load existing defaults if they exist
 	var defaults DefaultConfig
-	if _, err := os.Stat(defaultsFile); !os.IsNotExist(err) {
-		data, err := os.ReadFile(defaultsFile)
-		if err != nil {
-			return fmt.Errorf("failed to read defaults file: %w", err)
-		}
-
-		if err := json.Unmarshal(data, &defaults); err != nil {
-			return fmt.Errorf("failed to parse defaults file: %w", err)
-		}
+	if err := store.LoadJSON(defaultsFile, &defaults); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read defaults file: %w", err)
 	}
 
 	// Update the defaults
@@ -206,13 +297,8 @@ func UpdateDefaults(memory, javaArgs string) error {
 	}
 
 	// Save the defaults
-	data, err := json.MarshalIndent(defaults, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal defaults: %w", err)
-	}
-
-	if err := os.WriteFile(defaultsFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write defaults file: %w", err)
+	if err := store.SaveJSON(defaultsFile, defaults); err != nil {
+		return fmt.Errorf("failed to save defaults file: %w", err)
 	}
 
 	return nil
@@ -221,23 +307,17 @@ func UpdateDefaults(memory, javaArgs string) error {
 // GetDefaults gets the default configuration for new servers
 func GetDefaults() (DefaultConfig, error) {
 	// Create the defaults file path
-	defaultsFile := filepath.Join(configDir, "defaults.json")
+	defaultsFile := filepath.Join(layout.Config, "defaults.json")
 
 	// Load existing defaults if they exist
 	var defaults DefaultConfig
-	if _, err := os.Stat(defaultsFile); !os.IsNotExist(err) {
-		data, err := os.ReadFile(defaultsFile)
-		if err != nil {
-			return defaults, fmt.Errorf("failed to read defaults file: %w", err)
-		}
-
-		if err := json.Unmarshal(data, &defaults); err != nil {
-			return defaults, fmt.Errorf("failed to parse defaults file: %w", err)
-		}
-	} else {
+	err := store.LoadJSON(defaultsFile, &defaults)
+	if os.IsNotExist(err) {
 		// Set default values
 		defaults.Memory = "2G"
 		defaults.JavaArgs = "-XX:+UseG1GC -XX:+ParallelRefProcEnabled"
+	} else if err != nil {
+		return defaults, fmt.Errorf("failed to read defaults file: %w", err)
 	}
 
 	return defaults, nil
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// Environment variables that select where mcsrvr keeps its files. Child processes such as
+// supervisors inherit them, so they use the same directories as the command that started them.
+const (
+	// HomeEnv keeps all files of mcsrvr in one directory, like --home
+	HomeEnv = "MCSRVR_HOME"
+	// SystemEnv selects the system-wide layout when set to a true value such as 1, like --system
+	SystemEnv = "MCSRVR_SYSTEM"
+)
+
+// Options select the layout of the mcsrvr directories. Home takes precedence over System,
+// and both take precedence over the environment.
+type Options struct {
+	Home   string
+	System bool
+}
+
+// Layout holds the directories mcsrvr keeps its files in
+type Layout struct {
+	// Config holds config.json and defaults.json
+	Config string
+	// State holds the active servers, the schedule and crash histories and the console sockets
+	State string
+	// Data holds the backup repository and the download cache
+	Data string
+}
+
+// layout is the layout resolved by Initialize
+var layout Layout
+
+// ResolveLayout returns the directories mcsrvr uses for the given options:
+//   - a home directory from --home or MCSRVR_HOME keeps everything in that directory
+//   - the system layout from --system or MCSRVR_SYSTEM uses /etc/mcsrvr for the configuration
+//     and /var/lib/mcsrvr for state and data, or %ProgramData%\mcsrvr on Windows
+//   - otherwise ~/.mcsrvr is used if it exists, as it was the only layout of older versions
+//   - otherwise the XDG base directories are used on Linux and other Unix-like systems,
+//     and ~/.mcsrvr on Windows and macOS
+func ResolveLayout(options Options) (Layout, error) {
+	home := options.Home
+	if home == "" && !options.System {
+		home = os.Getenv(HomeEnv)
+	}
+	if home != "" {
+		home, err := filepath.Abs(home)
+		if err != nil {
+			return Layout{}, fmt.Errorf("failed to resolve mcsrvr home directory: %w", err)
+		}
+		return Layout{Config: home, State: home, Data: home}, nil
+	}
+
+	if options.System || isTrue(os.Getenv(SystemEnv)) {
+		return systemLayout(), nil
+	}
+
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return Layout{}, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	legacy := filepath.Join(userHome, ".mcsrvr")
+	if _, err := os.Stat(legacy); err == nil || runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return Layout{Config: legacy, State: legacy, Data: legacy}, nil
+	}
+
+	return Layout{
+		Config: filepath.Join(xdgDir("XDG_CONFIG_HOME", userHome, ".config"), "mcsrvr"),
+		State:  filepath.Join(xdgDir("XDG_STATE_HOME", userHome, ".local", "state"), "mcsrvr"),
+		Data:   filepath.Join(xdgDir("XDG_DATA_HOME", userHome, ".local", "share"), "mcsrvr"),
+	}, nil
+}
+
+// systemLayout returns the directories of a system-wide installation, used for servers
+// that run under a dedicated service account
+func systemLayout() Layout {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		dir := filepath.Join(programData, "mcsrvr")
+		return Layout{Config: dir, State: dir, Data: dir}
+	}
+
+	return Layout{Config: "/etc/mcsrvr", State: "/var/lib/mcsrvr", Data: "/var/lib/mcsrvr"}
+}
+
+// xdgDir returns the directory in an XDG environment variable, or its default below the
+// user's home directory. Relative paths are invalid according to the specification and ignored.
+func xdgDir(env, userHome string, defaultPath ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(append([]string{userHome}, defaultPath...)...)
+}
+
+// isTrue reports whether an environment variable enables a setting
+func isTrue(value string) bool {
+	enabled, _ := strconv.ParseBool(value)
+	return enabled
+}
+
+// ConfigDir returns the directory of config.json and defaults.json
+func ConfigDir() string {
+	return layout.Config
+}
+
+// StateDir returns the directory of the active servers, histories and console sockets
+func StateDir() string {
+	return layout.State
+}
+
+// DataDir returns the directory of the backup repository and the download cache
+func DataDir() string {
+	return layout.Data
+}
@@ -0,0 +1,349 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+)
+
+// Offline makes the download functions use only jars that are already in the cache. Lookups
+// that need the network, such as resolving "latest", fail instead of making a request.
+var Offline bool
+
+// noBuild is the build component of cache keys for providers without builds
+const noBuild = "-"
+
+// The cache stores every jar under <provider>/<version>/<build>/<algorithm>-<hash>/<file>,
+// so jars are shared by all servers that use the same version, and two different jars can
+// never be mistaken for each other.
+
+// CacheEntry is a server jar in the download cache
+type CacheEntry struct {
+	Provider string
+	Version  string
+	Build    string
+	Checksum Checksum
+	FileName string
+	Path     string
+	Size     int64
+	LastUsed time.Time
+}
+
+// CacheDir returns the directory of the download cache
+func CacheDir() string {
+	return filepath.Join(config.DataDir(), "cache")
+}
+
+// cacheDir returns the cache directory of a download's version and build, which holds
+// one directory per hash
+func (d *Download) cacheDir() string {
+	build := d.Build
+	if build == "" {
+		build = noBuild
+	}
+	return filepath.Join(CacheDir(), d.Provider, d.Version, build)
+}
+
+// ListCache lists the jars in the download cache, sorted by provider, version and build
+func ListCache() ([]CacheEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(CacheDir(), "*", "*", "*", "*", "*.jar"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, path := range paths {
+		entry, ok := parseCachePath(path)
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return compareBuilds(a.Build, b.Build) < 0
+	})
+
+	return entries, nil
+}
+
+// parseCachePath reads the key and hash of a cached jar from its path
+func parseCachePath(path string) (CacheEntry, bool) {
+	rel, err := filepath.Rel(CacheDir(), path)
+	if err != nil {
+		return CacheEntry{}, false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) != 5 {
+		return CacheEntry{}, false
+	}
+
+	algorithm, value, ok := strings.Cut(parts[3], "-")
+	if !ok {
+		return CacheEntry{}, false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return CacheEntry{}, false
+	}
+
+	build := parts[2]
+	if build == noBuild {
+		build = ""
+	}
+
+	return CacheEntry{
+		Provider: parts[0],
+		Version:  parts[1],
+		Build:    build,
+		Checksum: Checksum{Algorithm: algorithm, Value: value},
+		FileName: parts[4],
+		Path:     path,
+		Size:     info.Size(),
+		LastUsed: info.ModTime(),
+	}, true
+}
+
+// findCached finds the jar of a download in the cache. If the download has no checksum,
+// any hash matches.
+func findCached(d *Download) (*CacheEntry, bool) {
+	hashPattern := "*"
+	if d.Checksum.Value != "" {
+		hashPattern = d.Checksum.Algorithm + "-" + strings.ToLower(d.Checksum.Value)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(d.cacheDir(), hashPattern, d.FileName))
+	for _, path := range paths {
+		if entry, ok := parseCachePath(path); ok {
+			return &entry, true
+		}
+	}
+	return nil, false
+}
+
+// latestCached finds the newest cached build of a provider's version. If build is not
+// empty, only that build matches.
+func latestCached(provider, version, build string) (*CacheEntry, bool) {
+	entries, err := ListCache()
+	if err != nil {
+		return nil, false
+	}
+
+	var latest *CacheEntry
+	for i, entry := range entries {
+		if entry.Provider == provider && entry.Version == version && (build == "" || entry.Build == build) {
+			// The entries are sorted, so the last match is the newest build
+			latest = &entries[i]
+		}
+	}
+	return latest, latest != nil
+}
+
+// compareBuilds compares two builds, numerically if both are numbers
+func compareBuilds(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return na - nb
+	}
+	return strings.Compare(a, b)
+}
+
+// fetchJar copies the jar of a download into the server directory. The jar comes from the cache
+// if it is there, otherwise it is downloaded into the cache first. The checksum of the
+// provider is used to verify the jar; without one, the jar is cached under its SHA-256.
+func fetchJar(d *Download, serverPath string) (string, error) {
+	if entry, ok := findCached(d); ok {
+		jarPath, err := useCached(entry, serverPath)
+		if err == nil {
+			return jarPath, nil
+		}
+		fmt.Printf("Warning: Ignoring cached %s: %v\n", entry.FileName, err)
+	}
+
+	if Offline {
+		return "", fmt.Errorf("%s %s is not in the download cache and offline mode is enabled", d.Provider, d.Version)
+	}
+
+	cachedPath, err := downloadToCache(d)
+	if err != nil {
+		return "", err
+	}
+
+	jarPath := filepath.Join(serverPath, d.FileName)
+	if err := copyFile(cachedPath, jarPath); err != nil {
+		return "", fmt.Errorf("failed to copy jar from cache: %w", err)
+	}
+	return jarPath, nil
+}
+
+// useCached verifies a cached jar and copies it into the server directory. A jar that fails
+// verification is removed from the cache.
+func useCached(entry *CacheEntry, serverPath string) (string, error) {
+	h, err := entry.Checksum.newHash()
+	if err != nil {
+		return "", err
+	}
+	if err := verifyChecksum(entry.Path, h, entry.Checksum.Value); err != nil {
+		os.RemoveAll(filepath.Dir(entry.Path))
+		return "", err
+	}
+
+	fmt.Printf("Using cached %s\n", entry.FileName)
+	jarPath := filepath.Join(serverPath, entry.FileName)
+	if err := copyFile(entry.Path, jarPath); err != nil {
+		return "", fmt.Errorf("failed to copy jar from cache: %w", err)
+	}
+
+	// Record the use so 'mcsrvr cache clean --unused-for' keeps jars that are still needed
+	now := time.Now()
+	os.Chtimes(entry.Path, now, now)
+
+	return jarPath, nil
+}
+
+// downloadToCache downloads the jar of a download into the cache and returns its cached path
+func downloadToCache(d *Download) (string, error) {
+	if d.Checksum.Value != "" {
+		hashDir := filepath.Join(d.cacheDir(), d.Checksum.Algorithm+"-"+strings.ToLower(d.Checksum.Value))
+		if err := os.MkdirAll(hashDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create cache directory: %w", err)
+		}
+
+		cachedPath := filepath.Join(hashDir, d.FileName)
+		if err := downloadFile(d.URL, cachedPath, d.Checksum); err != nil {
+			return "", err
+		}
+		return cachedPath, nil
+	}
+
+	// Without a published checksum the hash is only known after downloading
+	downloadDir := filepath.Join(d.cacheDir(), "download")
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	downloadPath := filepath.Join(downloadDir, d.FileName)
+	if err := downloadFile(d.URL, downloadPath, Checksum{}); err != nil {
+		return "", err
+	}
+
+	sum, err := fileSHA256(downloadPath)
+	if err != nil {
+		return "", err
+	}
+	hashDir := filepath.Join(d.cacheDir(), ChecksumSHA256+"-"+sum)
+	if err := os.MkdirAll(hashDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	cachedPath := filepath.Join(hashDir, d.FileName)
+	if err := os.Rename(downloadPath, cachedPath); err != nil {
+		return "", fmt.Errorf("failed to move download into cache: %w", err)
+	}
+	os.Remove(downloadDir)
+
+	return cachedPath, nil
+}
+
+// fetchOffline copies the cached jar of a version and build into the server directory without
+// using the network. An empty build selects the newest cached build.
+func fetchOffline(provider, serverPath, version, build string) (*Download, string, error) {
+	if version == VersionLatest || version == VersionLatestSnapshot {
+		return nil, "", fmt.Errorf("offline mode needs an explicit version instead of '%s'", version)
+	}
+
+	entry, ok := latestCached(provider, version, build)
+	if !ok {
+		return nil, "", fmt.Errorf("%s %s is not in the download cache and offline mode is enabled", provider, version)
+	}
+
+	jarPath, err := useCached(entry, serverPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	download := &Download{
+		Provider: entry.Provider,
+		Version:  entry.Version,
+		Build:    entry.Build,
+		FileName: entry.FileName,
+		Checksum: entry.Checksum,
+	}
+	return download, jarPath, nil
+}
+
+// RemoveCached removes a jar from the cache, along with directories left empty
+func RemoveCached(entry CacheEntry) error {
+	if err := os.RemoveAll(filepath.Dir(entry.Path)); err != nil {
+		return err
+	}
+
+	// Remove the build and version directories if they are now empty
+	dir := filepath.Dir(filepath.Dir(entry.Path))
+	for i := 0; i < 3; i++ {
+		if os.Remove(dir) != nil {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyFile copies a file through a temporary file, so the destination is never left incomplete
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmpPath := dst + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, dst)
+}
@@ -0,0 +1,310 @@
+package downloader
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/util"
+)
+
+// Checksum algorithms published by the download providers
+const (
+	ChecksumMD5    = "md5"
+	ChecksumSHA1   = "sha1"
+	ChecksumSHA256 = "sha256"
+)
+
+// maxDownloadAttempts is how often an interrupted download is resumed before giving up
+const maxDownloadAttempts = 5
+
+// stallTimeout is how long a download may go without receiving data before it is retried.
+// There is deliberately no limit on the total time, so large files work on slow links.
+const stallTimeout = 60 * time.Second
+
+// Checksum is the expected hash of a download. An empty Algorithm skips verification.
+type Checksum struct {
+	Algorithm string
+	Value     string
+}
+
+// newHash returns a hash for the checksum algorithm
+func (c Checksum) newHash() (hash.Hash, error) {
+	switch c.Algorithm {
+	case ChecksumMD5:
+		return md5.New(), nil
+	case ChecksumSHA1:
+		return sha1.New(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", c.Algorithm)
+	}
+}
+
+// httpClient is used for downloads. It limits how long connecting and waiting for a response
+// may take, but not how long the body takes to arrive.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	},
+}
+
+// apiTimeout limits the whole of a request to an API, such as a version manifest or a
+// checksum. Unlike downloads, their responses are small, so a slow one has stalled.
+const apiTimeout = 60 * time.Second
+
+// apiClient is used for API requests. It shares the transport of the download client.
+var apiClient = &http.Client{
+	Transport: httpClient.Transport,
+	Timeout:   apiTimeout,
+}
+
+// Get requests a URL of an API with the timeouts mcsrvr uses for its own API requests.
+// The caller must close the body of the response.
+func Get(url string) (*http.Response, error) {
+	return apiClient.Get(url)
+}
+
+// downloadFile downloads a file from a URL to a local path. The file is written to a ".part"
+// file next to the destination, which is resumed with Range requests if the download is
+// interrupted, and only renamed into place once it has been verified against the checksum.
+func downloadFile(url, filePath string, checksum Checksum) error {
+	partPath := filePath + ".part"
+
+	var err error
+	for attempt := 1; attempt <= maxDownloadAttempts; attempt++ {
+		if attempt > 1 {
+			fmt.Printf("Download interrupted (%v), resuming (attempt %d of %d)...\n", err, attempt, maxDownloadAttempts)
+			time.Sleep(time.Duration(attempt-1) * 2 * time.Second)
+		}
+
+		err = downloadPart(url, partPath)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Verify the download before it replaces anything
+	if checksum.Algorithm != "" {
+		h, err := checksum.newHash()
+		if err != nil {
+			return err
+		}
+		if err := verifyChecksum(partPath, h, checksum.Value); err != nil {
+			// Do not resume from a corrupt file next time
+			os.Remove(partPath)
+			return err
+		}
+	}
+
+	if err := os.Rename(partPath, filePath); err != nil {
+		return fmt.Errorf("failed to move download into place: %w", err)
+	}
+
+	return nil
+}
+
+// DownloadFile downloads a file such as a plugin or mod, with the same resuming and
+// verification as server jars
+func DownloadFile(url, filePath string, checksum Checksum) error {
+	return downloadFile(url, filePath, checksum)
+}
+
+// VerifyFile checks that a file matches a checksum
+func VerifyFile(filePath string, checksum Checksum) error {
+	h, err := checksum.newHash()
+	if err != nil {
+		return err
+	}
+	return verifyChecksum(filePath, h, checksum.Value)
+}
+
+// downloadPart downloads a URL into partPath, continuing after the data already in it
+func downloadPart(url, partPath string) error {
+	// Continue after whatever an earlier attempt already downloaded
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := resp.ContentLength
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// The server continues where we left off
+		flags |= os.O_APPEND
+		if total >= 0 {
+			total += offset
+		}
+		if offset > 0 {
+			fmt.Printf("Resuming download at %s\n", util.FormatSize(offset))
+		}
+	case http.StatusOK:
+		// The server sent the whole file, so start over
+		flags |= os.O_TRUNC
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The part file is already complete
+		return nil
+	default:
+		return fmt.Errorf("failed to download file: %s", resp.Status)
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// Give up on this attempt if no data arrives for too long
+	stall := time.AfterFunc(stallTimeout, cancel)
+	defer stall.Stop()
+
+	progress := newProgressBar(offset, total)
+	_, err = io.Copy(out, io.TeeReader(resp.Body, writerFunc(func(p []byte) (int, error) {
+		stall.Reset(stallTimeout)
+		progress.add(int64(len(p)))
+		return len(p), nil
+	})))
+	progress.finish()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("no data received for %s", stallTimeout)
+		}
+		return err
+	}
+
+	return out.Close()
+}
+
+// verifyChecksum checks that the hash of a file matches the expected hex digest
+func verifyChecksum(filePath string, h hash.Hash, expected string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+// writerFunc adapts a function to io.Writer
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// progressBar prints the progress of a download. On a terminal it redraws a bar in place,
+// otherwise it only prints a summary when the download ends.
+type progressBar struct {
+	done        int64
+	total       int64
+	start       time.Time
+	startDone   int64
+	lastDraw    time.Time
+	interactive bool
+}
+
+// newProgressBar creates a progress bar for a download that already has done bytes of total.
+// A negative total means the size is unknown.
+func newProgressBar(done, total int64) *progressBar {
+	interactive := false
+	if info, err := os.Stdout.Stat(); err == nil {
+		interactive = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	return &progressBar{
+		done:        done,
+		total:       total,
+		start:       time.Now(),
+		startDone:   done,
+		interactive: interactive,
+	}
+}
+
+// add records newly downloaded bytes and redraws the bar at most a few times per second
+func (p *progressBar) add(n int64) {
+	p.done += n
+	if p.interactive && time.Since(p.lastDraw) >= 200*time.Millisecond {
+		p.draw()
+		p.lastDraw = time.Now()
+	}
+}
+
+// finish draws the final state of the bar
+func (p *progressBar) finish() {
+	if p.interactive {
+		p.draw()
+		fmt.Println()
+		return
+	}
+
+	elapsed := time.Since(p.start).Round(time.Second)
+	fmt.Printf("Downloaded %s in %s\n", util.FormatSize(p.done-p.startDone), elapsed)
+}
+
+// draw redraws the bar on the current line
+func (p *progressBar) draw() {
+	speed := ""
+	if elapsed := time.Since(p.start).Seconds(); elapsed > 0 {
+		speed = util.FormatSize(int64(float64(p.done-p.startDone)/elapsed)) + "/s"
+	}
+
+	if p.total <= 0 {
+		fmt.Printf("\r%s  %s   ", util.FormatSize(p.done), speed)
+		return
+	}
+
+	const width = 30
+	filled := int(p.done * width / p.total)
+	if filled > width {
+		filled = width
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+	fmt.Printf("\r[%s] %3d%%  %s / %s  %s   ", bar, p.done*100/p.total, util.FormatSize(p.done), util.FormatSize(p.total), speed)
+}
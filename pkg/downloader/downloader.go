@@ -1,250 +1,225 @@
 package downloader
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
-// PaperMCAPI represents the PaperMC API endpoints
+// Providers of server jars, which are also the server types
 const (
-	PaperMCBaseURL = "https://api.papermc.io/v2/projects"
-	PaperMCProject = "paper"
+	ProviderPaperMC   = "papermc"
+	ProviderFolia     = "folia"
+	ProviderVelocity  = "velocity"
+	ProviderWaterfall = "waterfall"
+	ProviderPurpur    = "purpur"
+	ProviderVanilla   = "vanilla"
+	ProviderFabric    = "fabric"
+	ProviderForge     = "forge"
+	ProviderNeoForge  = "neoforge"
+	ProviderQuilt     = "quilt"
 )
 
-// PaperMCVersionsResponse represents the response from the PaperMC API for versions
-type PaperMCVersionsResponse struct {
-	ProjectID   string   `json:"project_id"`
-	ProjectName string   `json:"project_name"`
-	Versions    []string `json:"versions"`
+// Channels of versions and builds. Providers map their own release channels onto these.
+const (
+	ChannelStable       = "stable"
+	ChannelSnapshot     = "snapshot"
+	ChannelExperimental = "experimental"
+	ChannelOld          = "old"
+)
+
+// Version is a game version offered by a provider
+type Version struct {
+	ID          string
+	Channel     string
+	ReleaseTime time.Time
 }
 
-// PaperMCBuildsResponse represents the response from the PaperMC API for builds
-type PaperMCBuildsResponse struct {
-	ProjectID   string `json:"project_id"`
-	ProjectName string `json:"project_name"`
-	Version     string `json:"version"`
-	Builds      []int  `json:"builds"`
+// Build is a build of the server software for a game version, such as a PaperMC build
+// or a Fabric loader version
+type Build struct {
+	ID      string
+	Channel string
+	Time    time.Time
 }
 
-// PaperMCBuildResponse represents the response from the PaperMC API for a specific build
-type PaperMCBuildResponse struct {
-	ProjectID   string `json:"project_id"`
-	ProjectName string `json:"project_name"`
-	Version     string `json:"version"`
-	Build       int    `json:"build"`
-	Downloads   struct {
-		Application struct {
-			Name   string `json:"name"`
-			Sha256 string `json:"sha256"`
-		} `json:"application"`
-	} `json:"downloads"`
+// Download is a resolved server jar
+type Download struct {
+	Provider string
+	Version  string
+	Build    string
+	URL      string
+	FileName string
+	Checksum Checksum
 }
 
-// DownloadPaperMC downloads the PaperMC server jar
-func DownloadPaperMC(serverPath, version string) (string, error) {
-	// If version is "latest", get the latest version
-	if version == "latest" {
-		var err error
-		version, err = getLatestPaperMCVersion()
-		if err != nil {
-			return "", fmt.Errorf("failed to get latest PaperMC version: %w", err)
-		}
-		fmt.Printf("Using latest PaperMC version: %s\n", version)
-	}
+// Launch describes how an installed server is started: either by running a jar, or, for
+// servers set up by the Forge and NeoForge installers, from the argument files of their run scripts.
+// Paths are relative to the server directory.
+type Launch struct {
+	Jar       string
+	ArgsFiles []string
+}
 
-	// Get the latest build for the version
-	latestBuild, err := getLatestPaperMCBuild(version)
-	if err != nil {
-		return "", fmt.Errorf("failed to get latest PaperMC build: %w", err)
-	}
+// Provider provides the server jars of one server type. Each provider lives in its own file
+// and registers itself in init, so adding a server type needs no changes elsewhere.
+type Provider interface {
+	// Name returns the server type used on the command line and in the configuration
+	Name() string
 
-	// Get the build details
-	buildDetails, err := getPaperMCBuildDetails(version, latestBuild)
-	if err != nil {
-		return "", fmt.Errorf("failed to get PaperMC build details: %w", err)
-	}
+	// Description returns the display name of the server software
+	Description() string
 
-	// Download the server jar
-	jarName := buildDetails.Downloads.Application.Name
-	downloadURL := fmt.Sprintf("%s/%s/versions/%s/builds/%d/downloads/%s",
-		PaperMCBaseURL, PaperMCProject, version, latestBuild, jarName)
+	// Proxy reports whether the server software is a proxy, which runs without nogui,
+	// has no EULA to accept, no RCON and is shut down with "end"
+	Proxy() bool
 
-	jarPath := filepath.Join(serverPath, jarName)
-	if err := downloadFile(downloadURL, jarPath); err != nil {
-		return "", fmt.Errorf("failed to download PaperMC server jar: %w", err)
-	}
+	// AddonDir returns the directory, relative to the server directory, that the server
+	// loads plugins or mods from, or an empty string if it loads neither
+	AddonDir() string
 
-	return jarPath, nil
+	// Versions lists the available game versions, newest first
+	Versions() ([]Version, error)
+
+	// Builds lists the builds of a game version, newest first. Providers without builds return none.
+	Builds(version string) ([]Build, error)
+
+	// Resolve finds the jar of a version and build. The version may be "latest", and an empty
+	// build selects the provider's default build.
+	Resolve(version, build string) (*Download, error)
+
+	// PostInstall runs after the jar has been placed in the server directory, using the given
+	// Java executable if it runs Java. It returns how the server is started, or nil if the
+	// downloaded jar is the server jar.
+	PostInstall(serverPath string, download *Download, javaPath string) (*Launch, error)
 }
 
-// DownloadVanilla downloads the vanilla Minecraft server jar
-func DownloadVanilla(serverPath, version string) (string, error) {
-	// For vanilla, we need to handle the version differently
-	// For now, we'll use a hardcoded URL for the latest version
-	// In a real implementation, we would need to fetch the version manifest from Mojang
-	
-	// This is a placeholder URL for the latest vanilla server
-	vanillaURL := "https://piston-data.mojang.com/v1/objects/8dd1a28015f51b1803213892b50b7b4fc76e594d/server.jar"
-	jarName := "minecraft_server." + version + ".jar"
-	jarPath := filepath.Join(serverPath, jarName)
-	
-	if err := downloadFile(vanillaURL, jarPath); err != nil {
-		return "", fmt.Errorf("failed to download vanilla server jar: %w", err)
+// providers holds the registered providers by name
+var providers = make(map[string]Provider)
+
+// Register adds a provider to the registry
+func Register(p Provider) {
+	if _, exists := providers[p.Name()]; exists {
+		panic(fmt.Sprintf("provider '%s' is already registered", p.Name()))
 	}
-	
-	return jarPath, nil
+	providers[p.Name()] = p
 }
 
-// DownloadFabric downloads the Fabric server jar
-func DownloadFabric(serverPath, mcVersion, loaderVersion string) (string, error) {
-	// Default installer version
-	installerVersion := "1.0.1"
-	
-	// If no loader version is provided, use the default
-	if loaderVersion == "" {
-		loaderVersion = "0.16.10"
-	}
-	
-	// Construct the URL for the Fabric server jar
-	fabricServerURL := fmt.Sprintf("https://meta.fabricmc.net/v2/versions/loader/%s/%s/%s/server/jar", 
-		mcVersion, loaderVersion, installerVersion)
-	
-	// Expected jar filename
-	jarName := fmt.Sprintf("fabric-server-mc.%s-loader.%s-launcher.%s.jar", 
-		mcVersion, loaderVersion, installerVersion)
-	jarPath := filepath.Join(serverPath, jarName)
-	
-	fmt.Printf("Downloading Fabric server jar for Minecraft %s with loader %s...\n", 
-		mcVersion, loaderVersion)
-	
-	// Download the Fabric server jar
-	if err := downloadFile(fabricServerURL, jarPath); err != nil {
-		return "", fmt.Errorf("failed to download Fabric server jar: %w", err)
-	}
-	
-	fmt.Println("Fabric server jar downloaded successfully")
-	fmt.Println("Note: Most mods will also require you to install Fabric API into the mods folder")
-	
-	// Create mods directory
-	modsDir := filepath.Join(serverPath, "mods")
-	if err := os.MkdirAll(modsDir, 0755); err != nil {
-		fmt.Printf("Warning: Failed to create mods directory: %v\n", err)
+// GetProvider returns the provider of a server type
+func GetProvider(name string) (Provider, error) {
+	p, exists := providers[name]
+	if !exists {
+		return nil, fmt.Errorf("unsupported server type '%s'. Supported types: %s", name, strings.Join(ProviderNames(), ", "))
 	}
-	
-	return jarPath, nil
+	return p, nil
 }
 
-// getLatestPaperMCVersion gets the latest PaperMC version
-func getLatestPaperMCVersion() (string, error) {
-	url := fmt.Sprintf("%s/%s", PaperMCBaseURL, PaperMCProject)
-	
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get PaperMC versions: %s", resp.Status)
+// ProviderNames returns the names of all registered providers in alphabetical order
+func ProviderNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
 	}
-	
-	var versionsResp PaperMCVersionsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&versionsResp); err != nil {
-		return "", err
+	sort.Strings(names)
+	return names
+}
+
+// IsProxy reports whether a server type is a proxy. Unknown types are not proxies.
+func IsProxy(serverType string) bool {
+	p, exists := providers[serverType]
+	return exists && p.Proxy()
+}
+
+// AddonDir returns the directory, relative to the server directory, that a server type loads
+// plugins or mods from, or an empty string if it loads neither. Unknown types load neither.
+func AddonDir(serverType string) string {
+	p, exists := providers[serverType]
+	if !exists {
+		return ""
 	}
-	
-	if len(versionsResp.Versions) == 0 {
-		return "", fmt.Errorf("no PaperMC versions found")
+	return p.AddonDir()
+}
+
+// StopCommand returns the console command that shuts down a server of a type
+func StopCommand(serverType string) string {
+	if IsProxy(serverType) {
+		return "end"
 	}
-	
-	// Return the latest version (last in the list)
-	return versionsResp.Versions[len(versionsResp.Versions)-1], nil
+	return "stop"
 }
 
-// getLatestPaperMCBuild gets the latest build for a PaperMC version
-func getLatestPaperMCBuild(version string) (int, error) {
-	url := fmt.Sprintf("%s/%s/versions/%s", PaperMCBaseURL, PaperMCProject, version)
-	
-	resp, err := http.Get(url)
+// Install resolves the jar of a server type's version and build, places it in the server
+// directory and runs the provider's post-install step with the given Java executable, which
+// may be empty to use java from the PATH. The jar comes from the download cache when possible.
+// It returns the resolved download and how the installed server is started.
+func Install(serverType, serverPath, version, build, javaPath string) (*Download, *Launch, error) {
+	p, err := GetProvider(serverType)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("failed to get PaperMC builds: %s", resp.Status)
+
+	if Offline {
+		// Without the network the newest matching jar in the cache is used
+		download, _, err := fetchOffline(p.Name(), serverPath, version, build)
+		if err != nil {
+			return nil, nil, err
+		}
+		launch, err := postInstall(p, serverPath, download, javaPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return download, launch, nil
 	}
-	
-	var buildsResp PaperMCBuildsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&buildsResp); err != nil {
-		return 0, err
+
+	download, err := Resolve(serverType, version, build)
+	if err != nil {
+		return nil, nil, err
 	}
-	
-	if len(buildsResp.Builds) == 0 {
-		return 0, fmt.Errorf("no PaperMC builds found for version %s", version)
+	if download.Version != version {
+		fmt.Printf("Using %s %s version: %s\n", version, p.Description(), download.Version)
 	}
-	
-	// Return the latest build (last in the list)
-	return buildsResp.Builds[len(buildsResp.Builds)-1], nil
-}
 
-// getPaperMCBuildDetails gets the details for a specific PaperMC build
-func getPaperMCBuildDetails(version string, build int) (*PaperMCBuildResponse, error) {
-	url := fmt.Sprintf("%s/%s/versions/%s/builds/%d", PaperMCBaseURL, PaperMCProject, version, build)
-	
-	resp, err := http.Get(url)
+	launch, err := InstallDownload(download, serverPath, javaPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get PaperMC build details: %s", resp.Status)
-	}
-	
-	var buildResp PaperMCBuildResponse
-	if err := json.NewDecoder(resp.Body).Decode(&buildResp); err != nil {
+	return download, launch, nil
+}
+
+// Resolve resolves a version and build of a server type to a download without fetching it
+func Resolve(serverType, version, build string) (*Download, error) {
+	p, err := GetProvider(serverType)
+	if err != nil {
 		return nil, err
 	}
-	
-	return &buildResp, nil
+	return p.Resolve(version, build)
 }
 
-// downloadFile downloads a file from a URL to a local path
-func downloadFile(url, filePath string) error {
-	// Create the file
-	out, err := os.Create(filePath)
+// InstallDownload places a resolved download in the server directory, from the download cache
+// when possible, and runs the provider's post-install step. It returns how the installed
+// server is started.
+func InstallDownload(download *Download, serverPath, javaPath string) (*Launch, error) {
+	p, err := GetProvider(download.Provider)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer out.Close()
-	
-	// Create a client with a timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+
+	if _, err := fetchJar(download, serverPath); err != nil {
+		return nil, fmt.Errorf("failed to download %s server jar: %w", p.Description(), err)
 	}
-	
-	// Get the data
-	resp, err := client.Get(url)
+	return postInstall(p, serverPath, download, javaPath)
+}
+
+// postInstall runs the post-install step of a provider. Without a launch of its own, the
+// downloaded jar is the server jar.
+func postInstall(p Provider, serverPath string, download *Download, javaPath string) (*Launch, error) {
+	launch, err := p.PostInstall(serverPath, download, javaPath)
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file: %s", resp.Status)
+		return nil, err
 	}
-	
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return err
+	if launch == nil {
+		launch = &Launch{Jar: download.FileName}
 	}
-	
-	return nil
+	return launch, nil
 }
@@ -0,0 +1,184 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FabricMetaURL is the base URL of the Fabric meta API
+const FabricMetaURL = "https://meta.fabricmc.net/v2"
+
+// FabricGameVersion represents a game version in the Fabric meta API
+type FabricGameVersion struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+// FabricLoaderVersion represents a loader version in the Fabric meta API
+type FabricLoaderVersion struct {
+	Build   int    `json:"build"`
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+// FabricInstallerVersion represents an installer version in the Fabric meta API
+type FabricInstallerVersion struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+// fabricProvider provides Fabric server launcher jars. The build of a Fabric server is
+// its loader version.
+type fabricProvider struct{}
+
+func init() {
+	Register(fabricProvider{})
+}
+
+// Name returns the server type of Fabric
+func (fabricProvider) Name() string {
+	return ProviderFabric
+}
+
+// Description returns the display name of Fabric
+func (fabricProvider) Description() string {
+	return "Fabric"
+}
+
+// Proxy reports that Fabric servers are not proxies
+func (fabricProvider) Proxy() bool {
+	return false
+}
+
+// AddonDir returns the directory Fabric servers load mods from
+func (fabricProvider) AddonDir() string {
+	return "mods"
+}
+
+// Versions lists the game versions supported by Fabric
+func (fabricProvider) Versions() ([]Version, error) {
+	var gameVersions []FabricGameVersion
+	if err := getJSON(FabricMetaURL+"/versions/game", &gameVersions); err != nil {
+		return nil, fmt.Errorf("failed to get Fabric game versions: %w", err)
+	}
+
+	// The meta API lists the newest version first
+	versions := make([]Version, 0, len(gameVersions))
+	for _, v := range gameVersions {
+		channel := ChannelStable
+		if !v.Stable {
+			channel = ChannelSnapshot
+		}
+		versions = append(versions, Version{ID: v.Version, Channel: channel})
+	}
+	return versions, nil
+}
+
+// Builds lists the loader versions available for a game version
+func (fabricProvider) Builds(version string) ([]Build, error) {
+	var loaders []struct {
+		Loader FabricLoaderVersion `json:"loader"`
+	}
+	if err := getJSON(fmt.Sprintf("%s/versions/loader/%s", FabricMetaURL, version), &loaders); err != nil {
+		return nil, fmt.Errorf("failed to get Fabric loader versions: %w", err)
+	}
+
+	builds := make([]Build, 0, len(loaders))
+	for _, l := range loaders {
+		channel := ChannelStable
+		if !l.Loader.Stable {
+			channel = ChannelExperimental
+		}
+		builds = append(builds, Build{ID: l.Loader.Version, Channel: channel})
+	}
+	return builds, nil
+}
+
+// Resolve returns the server launcher jar of a game version and loader version. The newest
+// stable game version and the newest stable loader compatible with the game version are used
+// if they are "latest" or empty, and the launcher is always built with the newest stable
+// installer. Fabric does not publish a checksum for the generated jar.
+func (p fabricProvider) Resolve(version, build string) (*Download, error) {
+	// If version is "latest", get the newest stable game version
+	if version == VersionLatest {
+		versions, err := p.Versions()
+		if err != nil {
+			return nil, err
+		}
+		version = ""
+		for _, v := range versions {
+			if v.Channel == ChannelStable {
+				version = v.ID
+				break
+			}
+		}
+		if version == "" {
+			return nil, fmt.Errorf("no Fabric game versions found")
+		}
+	}
+
+	// If no loader version is provided, use the newest stable loader for the game version
+	loaderVersion := build
+	if loaderVersion == "" || loaderVersion == VersionLatest {
+		builds, err := p.Builds(version)
+		if err != nil {
+			return nil, err
+		}
+		if len(builds) == 0 {
+			return nil, fmt.Errorf("Fabric does not support Minecraft %s", version)
+		}
+		loaderVersion = builds[0].ID
+		for _, b := range builds {
+			if b.Channel == ChannelStable {
+				loaderVersion = b.ID
+				break
+			}
+		}
+	}
+
+	installerVersion, err := getFabricInstaller()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Download{
+		Provider: ProviderFabric,
+		Version:  version,
+		Build:    loaderVersion,
+		URL: fmt.Sprintf("%s/versions/loader/%s/%s/%s/server/jar",
+			FabricMetaURL, version, loaderVersion, installerVersion),
+		FileName: fmt.Sprintf("fabric-server-mc.%s-loader.%s-launcher.%s.jar",
+			version, loaderVersion, installerVersion),
+	}, nil
+}
+
+// PostInstall creates the mods directory of a Fabric server
+func (fabricProvider) PostInstall(serverPath string, download *Download, javaPath string) (*Launch, error) {
+	fmt.Printf("Fabric server jar for Minecraft %s with loader %s is ready\n", download.Version, download.Build)
+	fmt.Println("Note: Most mods will also require you to install Fabric API into the mods folder")
+
+	// Create mods directory
+	modsDir := filepath.Join(serverPath, "mods")
+	if err := os.MkdirAll(modsDir, 0755); err != nil {
+		fmt.Printf("Warning: Failed to create mods directory: %v\n", err)
+	}
+
+	return nil, nil
+}
+
+// getFabricInstaller returns the newest stable Fabric installer version
+func getFabricInstaller() (string, error) {
+	var installers []FabricInstallerVersion
+	if err := getJSON(FabricMetaURL+"/versions/installer", &installers); err != nil {
+		return "", fmt.Errorf("failed to get Fabric installer versions: %w", err)
+	}
+
+	// The meta API lists the newest installer first
+	for _, installer := range installers {
+		if installer.Stable {
+			return installer.Version, nil
+		}
+	}
+	return "", fmt.Errorf("no stable Fabric installer found")
+}
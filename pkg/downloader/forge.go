@@ -0,0 +1,410 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Maven repositories of Forge and NeoForge
+const (
+	ForgeMavenURL      = "https://maven.minecraftforge.net/net/minecraftforge/forge"
+	ForgePromotionsURL = "https://files.minecraftforge.net/net/minecraftforge/forge/promotions_slim.json"
+	NeoForgeMavenURL   = "https://maven.neoforged.net/releases/net/neoforged/neoforge"
+)
+
+// argsFilePattern finds the argument files passed to java in the run scripts of the installers
+var argsFilePattern = regexp.MustCompile(`@([^\s"%$]+)`)
+
+// ForgePromotions represents the recommended and latest Forge build of each Minecraft version
+type ForgePromotions struct {
+	Promos map[string]string `json:"promos"`
+}
+
+// forgeProvider provides Forge servers. The build of a Forge server is its Forge version.
+type forgeProvider struct{}
+
+// neoForgeProvider provides NeoForge servers for Minecraft 1.20.2 and later.
+// The build of a NeoForge server is its NeoForge version.
+type neoForgeProvider struct{}
+
+func init() {
+	Register(forgeProvider{})
+	Register(neoForgeProvider{})
+}
+
+// Name returns the server type of Forge
+func (forgeProvider) Name() string {
+	return ProviderForge
+}
+
+// Description returns the display name of Forge
+func (forgeProvider) Description() string {
+	return "Forge"
+}
+
+// Proxy reports that Forge servers are not proxies
+func (forgeProvider) Proxy() bool {
+	return false
+}
+
+// AddonDir returns the directory Forge servers load mods from
+func (forgeProvider) AddonDir() string {
+	return "mods"
+}
+
+// Versions lists the Minecraft versions that have a recommended or latest Forge build
+func (forgeProvider) Versions() ([]Version, error) {
+	promotions, err := getForgePromotions()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var versions []Version
+	for promo := range promotions.Promos {
+		mcVersion := promo[:strings.LastIndex(promo, "-")]
+		if !seen[mcVersion] {
+			seen[mcVersion] = true
+			versions = append(versions, Version{ID: mcVersion, Channel: ChannelStable})
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i].ID, versions[j].ID) > 0
+	})
+	return versions, nil
+}
+
+// Builds lists the Forge versions of a Minecraft version. Builds newer than the recommended
+// build are experimental.
+func (forgeProvider) Builds(version string) ([]Build, error) {
+	metadata, err := getMavenMetadata(ForgeMavenURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Forge versions: %w", err)
+	}
+	promotions, err := getForgePromotions()
+	if err != nil {
+		return nil, err
+	}
+	recommended := promotions.Promos[version+"-recommended"]
+
+	var builds []Build
+	for _, v := range metadata.Versioning.Versions {
+		forgeVersion, found := strings.CutPrefix(v, version+"-")
+		if !found {
+			continue
+		}
+		channel := ChannelStable
+		if recommended == "" || compareVersions(forgeVersion, recommended) > 0 {
+			channel = ChannelExperimental
+		}
+		builds = append(builds, Build{ID: forgeVersion, Channel: channel})
+	}
+
+	sort.Slice(builds, func(i, j int) bool {
+		return compareVersions(builds[i].ID, builds[j].ID) > 0
+	})
+	return builds, nil
+}
+
+// Resolve finds the Forge installer of a Minecraft version and Forge version. Without a
+// Forge version, the recommended build is used, or the latest if none is recommended.
+func (p forgeProvider) Resolve(version, build string) (*Download, error) {
+	if version == VersionLatest {
+		versions, err := p.Versions()
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no Forge versions found")
+		}
+		version = versions[0].ID
+	}
+
+	if build == "" || build == VersionLatest {
+		promotions, err := getForgePromotions()
+		if err != nil {
+			return nil, err
+		}
+		if build == "" {
+			build = promotions.Promos[version+"-recommended"]
+		}
+		if build == "" || build == VersionLatest {
+			build = promotions.Promos[version+"-latest"]
+		}
+		if build == "" {
+			return nil, fmt.Errorf("no Forge builds found for Minecraft %s", version)
+		}
+	}
+
+	fullVersion := version + "-" + build
+	fileName := "forge-" + fullVersion + "-installer.jar"
+	return newMavenDownload(ProviderForge, version, build, fmt.Sprintf("%s/%s/%s", ForgeMavenURL, fullVersion, fileName), fileName)
+}
+
+// PostInstall runs the Forge installer in the server directory and detects how the
+// installed server is launched
+func (p forgeProvider) PostInstall(serverPath string, download *Download, javaPath string) (*Launch, error) {
+	if err := runInstaller(serverPath, download, javaPath, p.Description(), "--installServer"); err != nil {
+		return nil, err
+	}
+	return detectLaunch(serverPath)
+}
+
+// Name returns the server type of NeoForge
+func (neoForgeProvider) Name() string {
+	return ProviderNeoForge
+}
+
+// Description returns the display name of NeoForge
+func (neoForgeProvider) Description() string {
+	return "NeoForge"
+}
+
+// Proxy reports that NeoForge servers are not proxies
+func (neoForgeProvider) Proxy() bool {
+	return false
+}
+
+// AddonDir returns the directory NeoForge servers load mods from
+func (neoForgeProvider) AddonDir() string {
+	return "mods"
+}
+
+// Versions lists the Minecraft versions NeoForge has builds for
+func (neoForgeProvider) Versions() ([]Version, error) {
+	builds, err := getNeoForgeBuilds()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var versions []Version
+	for _, b := range builds {
+		if !seen[b.mcVersion] {
+			seen[b.mcVersion] = true
+			versions = append(versions, Version{ID: b.mcVersion, Channel: ChannelStable})
+		}
+	}
+	return versions, nil
+}
+
+// Builds lists the NeoForge versions of a Minecraft version
+func (neoForgeProvider) Builds(version string) ([]Build, error) {
+	builds, err := getNeoForgeBuilds()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Build
+	for _, b := range builds {
+		if b.mcVersion == version {
+			result = append(result, b.Build)
+		}
+	}
+	return result, nil
+}
+
+// Resolve finds the NeoForge installer of a Minecraft version and NeoForge version. Without
+// a NeoForge version, the newest stable build is used, or the newest beta if there is none.
+func (neoForgeProvider) Resolve(version, build string) (*Download, error) {
+	// A NeoForge version determines its Minecraft version
+	if version == VersionLatest && build != "" && build != VersionLatest {
+		version = neoForgeGameVersion(build)
+	}
+
+	if version == VersionLatest || build == "" || build == VersionLatest {
+		builds, err := getNeoForgeBuilds()
+		if err != nil {
+			return nil, err
+		}
+		if len(builds) == 0 {
+			return nil, fmt.Errorf("no NeoForge versions found")
+		}
+		if version == VersionLatest {
+			version = builds[0].mcVersion
+		}
+
+		// The builds are sorted newest first
+		newest := ""
+		stable := ""
+		for _, b := range builds {
+			if b.mcVersion != version {
+				continue
+			}
+			if newest == "" {
+				newest = b.ID
+			}
+			if stable == "" && b.Channel == ChannelStable {
+				stable = b.ID
+			}
+		}
+		if newest == "" {
+			return nil, fmt.Errorf("no NeoForge builds found for Minecraft %s", version)
+		}
+
+		if build == "" && stable != "" {
+			build = stable
+		} else {
+			build = newest
+		}
+	}
+
+	if mcVersion := neoForgeGameVersion(build); mcVersion != version {
+		return nil, fmt.Errorf("NeoForge %s is for Minecraft %s, not %s", build, mcVersion, version)
+	}
+
+	fileName := "neoforge-" + build + "-installer.jar"
+	return newMavenDownload(ProviderNeoForge, version, build, fmt.Sprintf("%s/%s/%s", NeoForgeMavenURL, build, fileName), fileName)
+}
+
+// PostInstall runs the NeoForge installer in the server directory and detects how the
+// installed server is launched
+func (p neoForgeProvider) PostInstall(serverPath string, download *Download, javaPath string) (*Launch, error) {
+	if err := runInstaller(serverPath, download, javaPath, p.Description(), "--installServer"); err != nil {
+		return nil, err
+	}
+	return detectLaunch(serverPath)
+}
+
+// neoForgeBuild is a NeoForge version together with the Minecraft version it is for
+type neoForgeBuild struct {
+	Build
+	mcVersion string
+}
+
+// getNeoForgeBuilds lists all NeoForge versions, newest first
+func getNeoForgeBuilds() ([]neoForgeBuild, error) {
+	metadata, err := getMavenMetadata(NeoForgeMavenURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NeoForge versions: %w", err)
+	}
+
+	var builds []neoForgeBuild
+	for _, v := range metadata.Versioning.Versions {
+		mcVersion := neoForgeGameVersion(v)
+		if mcVersion == "" {
+			continue
+		}
+		channel := ChannelStable
+		if strings.Contains(v, "-") {
+			channel = ChannelExperimental
+		}
+		builds = append(builds, neoForgeBuild{Build: Build{ID: v, Channel: channel}, mcVersion: mcVersion})
+	}
+
+	sort.Slice(builds, func(i, j int) bool {
+		return compareVersions(builds[i].ID, builds[j].ID) > 0
+	})
+	return builds, nil
+}
+
+// neoForgeGameVersion returns the Minecraft version of a NeoForge version. NeoForge versions
+// start with the minor and patch version of Minecraft, so 21.4.50 is for 1.21.4 and 21.0.10
+// is for 1.21. It returns an empty string for versions that do not follow this scheme.
+func neoForgeGameVersion(neoForgeVersion string) string {
+	parts := strings.SplitN(neoForgeVersion, ".", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	if _, err := strconv.Atoi(parts[0]); err != nil {
+		return ""
+	}
+	if _, err := strconv.Atoi(parts[1]); err != nil {
+		return ""
+	}
+
+	if parts[1] == "0" {
+		return "1." + parts[0]
+	}
+	return "1." + parts[0] + "." + parts[1]
+}
+
+// getForgePromotions gets the recommended and latest Forge builds
+func getForgePromotions() (*ForgePromotions, error) {
+	var promotions ForgePromotions
+	if err := getJSON(ForgePromotionsURL, &promotions); err != nil {
+		return nil, fmt.Errorf("failed to get Forge promotions: %w", err)
+	}
+	return &promotions, nil
+}
+
+// detectLaunch finds out how a server set up by a Forge or NeoForge installer is launched.
+// Installers for Minecraft 1.17 and later create run scripts that pass argument files such as
+// user_jvm_args.txt to java; older installers create a forge jar that is run with -jar.
+func detectLaunch(serverPath string) (*Launch, error) {
+	script := "run.sh"
+	if runtime.GOOS == "windows" {
+		script = "run.bat"
+	}
+
+	if content, err := os.ReadFile(filepath.Join(serverPath, script)); err == nil {
+		var argsFiles []string
+		for _, match := range argsFilePattern.FindAllStringSubmatch(string(content), -1) {
+			argsFiles = append(argsFiles, match[1])
+		}
+		if len(argsFiles) > 0 {
+			return &Launch{ArgsFiles: argsFiles}, nil
+		}
+	}
+
+	jars, _ := filepath.Glob(filepath.Join(serverPath, "forge-*.jar"))
+	for _, jar := range jars {
+		if !strings.HasSuffix(jar, "-installer.jar") {
+			return &Launch{Jar: filepath.Base(jar)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not find the run script or server jar created by the installer")
+}
+
+// compareVersions compares two dotted version strings such as 1.21.4 or 54.0.16, comparing
+// numeric parts as numbers. A version with a suffix such as -beta is older than the same
+// version without it. It returns a negative number if a is older, 0 if they are equal and a
+// positive number if a is newer.
+func compareVersions(a, b string) int {
+	split := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '-' })
+	}
+	partsA, partsB := split(a), split(b)
+
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numA, errA := strconv.Atoi(partsA[i])
+		numB, errB := strconv.Atoi(partsB[i])
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				return numA - numB
+			}
+		case errA == nil:
+			return 1
+		case errB == nil:
+			return -1
+		default:
+			if c := strings.Compare(partsA[i], partsB[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	// A longer version is newer if the extra part is a number and older if it is a suffix
+	switch {
+	case len(partsA) > len(partsB):
+		if _, err := strconv.Atoi(partsA[len(partsB)]); err != nil {
+			return -1
+		}
+		return 1
+	case len(partsA) < len(partsB):
+		if _, err := strconv.Atoi(partsB[len(partsA)]); err != nil {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// MavenMetadata represents the maven-metadata.xml of an artifact
+type MavenMetadata struct {
+	Versioning struct {
+		Versions []string `xml:"versions>version"`
+	} `xml:"versioning"`
+}
+
+// getMavenMetadata gets the maven-metadata.xml of an artifact in a maven repository
+func getMavenMetadata(artifactURL string) (*MavenMetadata, error) {
+	body, err := getText(artifactURL + "/maven-metadata.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata MavenMetadata
+	if err := xml.Unmarshal([]byte(body), &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse maven metadata: %w", err)
+	}
+	return &metadata, nil
+}
+
+// newMavenDownload returns the download of an installer in a maven repository. The SHA-1
+// that maven publishes next to the installer is used to verify it, if there is one.
+func newMavenDownload(provider, version, build, url, fileName string) (*Download, error) {
+	download := &Download{
+		Provider: provider,
+		Version:  version,
+		Build:    build,
+		URL:      url,
+		FileName: fileName,
+	}
+
+	sha1, err := getText(url + ".sha1")
+	if err != nil {
+		fmt.Printf("Warning: No checksum found for %s: %v\n", fileName, err)
+		return download, nil
+	}
+	download.Checksum = Checksum{Algorithm: ChecksumSHA1, Value: strings.TrimSpace(sha1)}
+	return download, nil
+}
+
+// getText gets the body of a URL as a string
+func getText(url string) (string, error) {
+	resp, err := apiClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// runInstaller runs an installer jar with the given arguments in the server directory,
+// removes the installer and creates the mods directory
+func runInstaller(serverPath string, download *Download, javaPath, description string, args ...string) error {
+	if javaPath == "" {
+		javaPath = "java"
+	}
+	java, err := exec.LookPath(javaPath)
+	if err != nil {
+		return fmt.Errorf("the %s installer needs Java: %w", description, err)
+	}
+
+	// The installer downloads the libraries and the vanilla server, which can take a while
+	fmt.Printf("Running the %s %s installer for Minecraft %s...\n", description, download.Build, download.Version)
+	cmd := exec.Command(java, append([]string{"-jar", download.FileName}, args...)...)
+	cmd.Dir = serverPath
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s installer failed: %w", description, err)
+	}
+
+	// The installer stays in the download cache, so it is not needed in the server directory
+	if err := os.Remove(filepath.Join(serverPath, download.FileName)); err != nil {
+		fmt.Printf("Warning: Failed to remove the installer: %v\n", err)
+	}
+	os.Remove(filepath.Join(serverPath, download.FileName+".log"))
+
+	// Create mods directory
+	modsDir := filepath.Join(serverPath, "mods")
+	if err := os.MkdirAll(modsDir, 0755); err != nil {
+		fmt.Printf("Warning: Failed to create mods directory: %v\n", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MojangManifestURL is the URL of the launcher version manifest. It is a variable so it can
+// point to a local server, for example in tests.
+var MojangManifestURL = "https://piston-meta.mojang.com/mc/game/version_manifest_v2.json"
+
+// Version aliases accepted for vanilla servers
+const (
+	VersionLatest         = "latest"
+	VersionLatestSnapshot = "latest-snapshot"
+)
+
+// MojangVersionManifest represents the launcher version manifest
+type MojangVersionManifest struct {
+	Latest struct {
+		Release  string `json:"release"`
+		Snapshot string `json:"snapshot"`
+	} `json:"latest"`
+	Versions []MojangVersion `json:"versions"`
+}
+
+// MojangVersion represents a version listed in the launcher version manifest
+type MojangVersion struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	URL         string    `json:"url"`
+	Time        time.Time `json:"time"`
+	ReleaseTime time.Time `json:"releaseTime"`
+	SHA1        string    `json:"sha1"`
+}
+
+// MojangVersionDetails represents the per-version JSON a manifest entry points to
+type MojangVersionDetails struct {
+	ID        string `json:"id"`
+	Downloads struct {
+		Server *MojangDownload `json:"server"`
+	} `json:"downloads"`
+}
+
+// MojangDownload represents a downloadable file of a version
+type MojangDownload struct {
+	SHA1 string `json:"sha1"`
+	Size int64  `json:"size"`
+	URL  string `json:"url"`
+}
+
+// GetMojangManifest fetches the launcher version manifest
+func GetMojangManifest() (*MojangVersionManifest, error) {
+	var manifest MojangVersionManifest
+	if err := getJSON(MojangManifestURL, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to get Mojang version manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Resolve finds a version in the manifest. Besides version IDs such as "1.21.4"
+// or "24w14a", it accepts "latest" for the newest release and "latest-snapshot" for the newest snapshot.
+func (m *MojangVersionManifest) Resolve(version string) (MojangVersion, error) {
+	switch version {
+	case VersionLatest:
+		version = m.Latest.Release
+	case VersionLatestSnapshot:
+		version = m.Latest.Snapshot
+	}
+
+	for _, v := range m.Versions {
+		if v.ID == version {
+			return v, nil
+		}
+	}
+
+	return MojangVersion{}, fmt.Errorf("unknown Minecraft version: %s", version)
+}
+
+// GetMojangVersionDetails fetches the per-version JSON of a version
+func GetMojangVersionDetails(version MojangVersion) (*MojangVersionDetails, error) {
+	var details MojangVersionDetails
+	if err := getJSON(version.URL, &details); err != nil {
+		return nil, fmt.Errorf("failed to get details of version %s: %w", version.ID, err)
+	}
+	return &details, nil
+}
+
+// getJSON fetches a URL and decodes the JSON response into v
+func getJSON(url string, v interface{}) error {
+	resp, err := apiClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,176 @@
+package downloader
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeJar is the content of the server jar served by the fake Mojang server
+var fakeJar = []byte("not really a server jar")
+
+// newFakeMojang starts a server with a launcher version manifest, the per-version JSON of
+// its versions and a server jar, and points MojangManifestURL to it
+func newFakeMojang(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	sum := sha1.Sum(fakeJar)
+	jarSHA1 := hex.EncodeToString(sum[:])
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	details := func(id string, withServer bool) interface{} {
+		d := map[string]interface{}{"id": id, "downloads": map[string]interface{}{}}
+		if withServer {
+			d["downloads"] = map[string]interface{}{
+				"server": map[string]interface{}{
+					"sha1": jarSHA1,
+					"size": len(fakeJar),
+					"url":  srv.URL + "/jars/" + id + ".jar",
+				},
+			}
+		}
+		return d
+	}
+
+	mux.HandleFunc("/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"latest": map[string]string{"release": "1.21.4", "snapshot": "25w02a"},
+			"versions": []map[string]string{
+				{"id": "25w02a", "type": "snapshot", "url": srv.URL + "/v/25w02a.json"},
+				{"id": "1.21.4", "type": "release", "url": srv.URL + "/v/1.21.4.json"},
+				{"id": "1.21.3", "type": "release", "url": srv.URL + "/v/1.21.3.json"},
+				{"id": "a1.0.4", "type": "old_alpha", "url": srv.URL + "/v/a1.0.4.json"},
+			},
+		})
+	})
+	for _, id := range []string{"25w02a", "1.21.4", "1.21.3"} {
+		mux.HandleFunc("/v/"+id+".json", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, details(id, true))
+		})
+	}
+	mux.HandleFunc("/v/a1.0.4.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, details("a1.0.4", false))
+	})
+	mux.HandleFunc("/jars/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(fakeJar)
+	})
+
+	previous := MojangManifestURL
+	MojangManifestURL = srv.URL + "/manifest.json"
+	t.Cleanup(func() { MojangManifestURL = previous })
+
+	return srv
+}
+
+func TestVanillaResolve(t *testing.T) {
+	srv := newFakeMojang(t)
+	sum := sha1.Sum(fakeJar)
+	jarSHA1 := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"latest", "1.21.4"},
+		{"latest-snapshot", "25w02a"},
+		{"1.21.3", "1.21.3"},
+	}
+
+	for _, tt := range tests {
+		download, err := vanillaProvider{}.Resolve(tt.version, "")
+		if err != nil {
+			t.Errorf("Resolve(%q) returned error: %v", tt.version, err)
+			continue
+		}
+		if download.Version != tt.want {
+			t.Errorf("Resolve(%q) version = %q, want %q", tt.version, download.Version, tt.want)
+		}
+		if wantURL := srv.URL + "/jars/" + tt.want + ".jar"; download.URL != wantURL {
+			t.Errorf("Resolve(%q) URL = %q, want %q", tt.version, download.URL, wantURL)
+		}
+		if download.Checksum != (Checksum{Algorithm: ChecksumSHA1, Value: jarSHA1}) {
+			t.Errorf("Resolve(%q) checksum = %+v, want SHA-1 %s", tt.version, download.Checksum, jarSHA1)
+		}
+	}
+
+	for _, version := range []string{"1.0.0", "a1.0.4"} {
+		if _, err := (vanillaProvider{}).Resolve(version, ""); err == nil {
+			t.Errorf("Resolve(%q) returned no error", version)
+		}
+	}
+	if _, err := (vanillaProvider{}).Resolve("1.21.4", "1"); err == nil {
+		t.Error("Resolve with a build returned no error")
+	}
+}
+
+func TestVanillaVersions(t *testing.T) {
+	newFakeMojang(t)
+
+	versions, err := vanillaProvider{}.Versions()
+	if err != nil {
+		t.Fatalf("Versions returned error: %v", err)
+	}
+	want := []Version{
+		{ID: "25w02a", Channel: ChannelSnapshot},
+		{ID: "1.21.4", Channel: ChannelStable},
+		{ID: "1.21.3", Channel: ChannelStable},
+		{ID: "a1.0.4", Channel: ChannelOld},
+	}
+	if len(versions) != len(want) {
+		t.Fatalf("Versions returned %d versions, want %d", len(versions), len(want))
+	}
+	for i := range want {
+		if versions[i].ID != want[i].ID || versions[i].Channel != want[i].Channel {
+			t.Errorf("version %d = %s (%s), want %s (%s)", i, versions[i].ID, versions[i].Channel, want[i].ID, want[i].Channel)
+		}
+	}
+}
+
+func TestDownloadVerifiesSHA1(t *testing.T) {
+	newFakeMojang(t)
+
+	download, err := vanillaProvider{}.Resolve("latest", "")
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	dir := t.TempDir()
+
+	// A jar that matches the published SHA-1 is moved into place
+	path := filepath.Join(dir, download.FileName)
+	if err := DownloadFile(download.URL, path, download.Checksum); err != nil {
+		t.Fatalf("DownloadFile returned error: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != string(fakeJar) {
+		t.Errorf("downloaded jar = %q, %v, want %q", data, err, fakeJar)
+	}
+	if err := VerifyFile(path, download.Checksum); err != nil {
+		t.Errorf("VerifyFile returned error: %v", err)
+	}
+
+	// A jar that does not match is rejected and neither it nor its part file is kept
+	bad := filepath.Join(dir, "bad.jar")
+	checksum := Checksum{Algorithm: ChecksumSHA1, Value: "0000000000000000000000000000000000000000"}
+	if err := DownloadFile(download.URL, bad, checksum); err == nil {
+		t.Error("DownloadFile accepted a jar with the wrong SHA-1")
+	}
+	for _, p := range []string{bad, bad + ".part"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s exists after a failed verification", filepath.Base(p))
+		}
+	}
+	if err := VerifyFile(path, checksum); err == nil {
+		t.Error("VerifyFile accepted a file with the wrong SHA-1")
+	}
+}
@@ -0,0 +1,188 @@
+package downloader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PaperMCBaseURL is the base URL of the PaperMC API, which serves every PaperMC project
+const PaperMCBaseURL = "https://api.papermc.io/v2/projects"
+
+// Projects on the PaperMC API
+const (
+	PaperMCProjectPaper     = "paper"
+	PaperMCProjectFolia     = "folia"
+	PaperMCProjectVelocity  = "velocity"
+	PaperMCProjectWaterfall = "waterfall"
+)
+
+// PaperMCVersionsResponse represents the response from the PaperMC API for versions
+type PaperMCVersionsResponse struct {
+	ProjectID   string   `json:"project_id"`
+	ProjectName string   `json:"project_name"`
+	Versions    []string `json:"versions"`
+}
+
+// PaperMCBuildsResponse represents the response from the PaperMC API for the builds of a version
+type PaperMCBuildsResponse struct {
+	ProjectID   string                 `json:"project_id"`
+	ProjectName string                 `json:"project_name"`
+	Version     string                 `json:"version"`
+	Builds      []PaperMCBuildResponse `json:"builds"`
+}
+
+// PaperMCBuildResponse represents a build in the PaperMC API
+type PaperMCBuildResponse struct {
+	Build     int       `json:"build"`
+	Time      time.Time `json:"time"`
+	Channel   string    `json:"channel"`
+	Downloads struct {
+		Application struct {
+			Name   string `json:"name"`
+			Sha256 string `json:"sha256"`
+		} `json:"application"`
+	} `json:"downloads"`
+}
+
+// paperMCProvider provides the jars of a project on the PaperMC API
+type paperMCProvider struct {
+	name        string
+	project     string
+	description string
+	proxy       bool
+}
+
+func init() {
+	Register(paperMCProvider{name: ProviderPaperMC, project: PaperMCProjectPaper, description: "PaperMC"})
+	Register(paperMCProvider{name: ProviderFolia, project: PaperMCProjectFolia, description: "Folia"})
+	Register(paperMCProvider{name: ProviderVelocity, project: PaperMCProjectVelocity, description: "Velocity", proxy: true})
+	Register(paperMCProvider{name: ProviderWaterfall, project: PaperMCProjectWaterfall, description: "Waterfall", proxy: true})
+}
+
+// Name returns the server type of the project
+func (p paperMCProvider) Name() string {
+	return p.name
+}
+
+// Description returns the display name of the project
+func (p paperMCProvider) Description() string {
+	return p.description
+}
+
+// Proxy reports whether the project is a proxy, such as Velocity or Waterfall
+func (p paperMCProvider) Proxy() bool {
+	return p.proxy
+}
+
+// AddonDir returns the directory the project loads plugins from. PaperMC servers and the
+// Velocity and Waterfall proxies all load plugins.
+func (p paperMCProvider) AddonDir() string {
+	return "plugins"
+}
+
+// Versions lists the versions the project has builds for
+func (p paperMCProvider) Versions() ([]Version, error) {
+	var versionsResp PaperMCVersionsResponse
+	if err := getJSON(fmt.Sprintf("%s/%s", PaperMCBaseURL, p.project), &versionsResp); err != nil {
+		return nil, fmt.Errorf("failed to get %s versions: %w", p.description, err)
+	}
+
+	// The API lists the oldest version first
+	versions := make([]Version, 0, len(versionsResp.Versions))
+	for i := len(versionsResp.Versions) - 1; i >= 0; i-- {
+		id := versionsResp.Versions[i]
+		channel := ChannelStable
+		if strings.Contains(id, "-pre") || strings.Contains(id, "-rc") || strings.HasSuffix(id, "-SNAPSHOT") {
+			channel = ChannelSnapshot
+		}
+		versions = append(versions, Version{ID: id, Channel: channel})
+	}
+	return versions, nil
+}
+
+// Builds lists the builds of a version of the project
+func (p paperMCProvider) Builds(version string) ([]Build, error) {
+	paperBuilds, err := p.getBuilds(version)
+	if err != nil {
+		return nil, err
+	}
+
+	builds := make([]Build, 0, len(paperBuilds))
+	for i := len(paperBuilds) - 1; i >= 0; i-- {
+		b := paperBuilds[i]
+		channel := ChannelStable
+		if b.Channel != "default" {
+			channel = ChannelExperimental
+		}
+		builds = append(builds, Build{ID: strconv.Itoa(b.Build), Channel: channel, Time: b.Time})
+	}
+	return builds, nil
+}
+
+// Resolve finds the jar of a version and build of the project. The latest version and build
+// are used if they are "latest" or empty.
+func (p paperMCProvider) Resolve(version, build string) (*Download, error) {
+	// If version is "latest", get the latest version
+	if version == VersionLatest {
+		versions, err := p.Versions()
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no %s versions found", p.description)
+		}
+		version = versions[0].ID
+	}
+
+	paperBuilds, err := p.getBuilds(version)
+	if err != nil {
+		return nil, err
+	}
+	if len(paperBuilds) == 0 {
+		return nil, fmt.Errorf("no %s builds found for version %s", p.description, version)
+	}
+
+	// Use the latest build unless a specific one was requested
+	selected := paperBuilds[len(paperBuilds)-1]
+	if build != "" && build != VersionLatest {
+		found := false
+		for _, b := range paperBuilds {
+			if strconv.Itoa(b.Build) == build {
+				selected = b
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown %s build %s for version %s", p.description, build, version)
+		}
+	}
+
+	jarName := selected.Downloads.Application.Name
+	return &Download{
+		Provider: p.name,
+		Version:  version,
+		Build:    strconv.Itoa(selected.Build),
+		URL: fmt.Sprintf("%s/%s/versions/%s/builds/%d/downloads/%s",
+			PaperMCBaseURL, p.project, version, selected.Build, jarName),
+		FileName: jarName,
+		Checksum: Checksum{Algorithm: ChecksumSHA256, Value: selected.Downloads.Application.Sha256},
+	}, nil
+}
+
+// PostInstall does nothing for PaperMC projects
+func (paperMCProvider) PostInstall(serverPath string, download *Download, javaPath string) (*Launch, error) {
+	return nil, nil
+}
+
+// getBuilds gets the builds of a version of the project, oldest first
+func (p paperMCProvider) getBuilds(version string) ([]PaperMCBuildResponse, error) {
+	var buildsResp PaperMCBuildsResponse
+	url := fmt.Sprintf("%s/%s/versions/%s/builds", PaperMCBaseURL, p.project, version)
+	if err := getJSON(url, &buildsResp); err != nil {
+		return nil, fmt.Errorf("failed to get %s builds: %w", p.description, err)
+	}
+	return buildsResp.Builds, nil
+}
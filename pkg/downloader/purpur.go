@@ -0,0 +1,156 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PurpurBaseURL is the base URL of the Purpur API
+const PurpurBaseURL = "https://api.purpurmc.org/v2/purpur"
+
+// PurpurVersionsResponse represents the response from the Purpur API for versions
+type PurpurVersionsResponse struct {
+	Project  string   `json:"project"`
+	Versions []string `json:"versions"`
+}
+
+// PurpurBuildsResponse represents the response from the Purpur API for the builds of a version
+type PurpurBuildsResponse struct {
+	Project string `json:"project"`
+	Version string `json:"version"`
+	Builds  struct {
+		Latest string   `json:"latest"`
+		All    []string `json:"all"`
+	} `json:"builds"`
+}
+
+// PurpurBuildResponse represents the response from the Purpur API for a specific build
+type PurpurBuildResponse struct {
+	Project   string `json:"project"`
+	Version   string `json:"version"`
+	Build     string `json:"build"`
+	Result    string `json:"result"`
+	Timestamp int64  `json:"timestamp"`
+	MD5       string `json:"md5"`
+}
+
+// purpurProvider provides Purpur server jars
+type purpurProvider struct{}
+
+func init() {
+	Register(purpurProvider{})
+}
+
+// Name returns the server type of Purpur
+func (purpurProvider) Name() string {
+	return ProviderPurpur
+}
+
+// Description returns the display name of Purpur
+func (purpurProvider) Description() string {
+	return "Purpur"
+}
+
+// Proxy reports that Purpur servers are not proxies
+func (purpurProvider) Proxy() bool {
+	return false
+}
+
+// AddonDir returns the directory Purpur servers load plugins from
+func (purpurProvider) AddonDir() string {
+	return "plugins"
+}
+
+// Versions lists the game versions Purpur has builds for
+func (purpurProvider) Versions() ([]Version, error) {
+	var versionsResp PurpurVersionsResponse
+	if err := getJSON(PurpurBaseURL, &versionsResp); err != nil {
+		return nil, fmt.Errorf("failed to get Purpur versions: %w", err)
+	}
+
+	// The API lists the oldest version first
+	versions := make([]Version, 0, len(versionsResp.Versions))
+	for i := len(versionsResp.Versions) - 1; i >= 0; i-- {
+		id := versionsResp.Versions[i]
+		channel := ChannelStable
+		if strings.Contains(id, "-pre") || strings.Contains(id, "-rc") {
+			channel = ChannelSnapshot
+		}
+		versions = append(versions, Version{ID: id, Channel: channel})
+	}
+	return versions, nil
+}
+
+// Builds lists the Purpur builds of a game version. The API only lists build numbers,
+// so the builds have no time.
+func (purpurProvider) Builds(version string) ([]Build, error) {
+	buildsResp, err := getPurpurBuilds(version)
+	if err != nil {
+		return nil, err
+	}
+
+	builds := make([]Build, 0, len(buildsResp.Builds.All))
+	for i := len(buildsResp.Builds.All) - 1; i >= 0; i-- {
+		builds = append(builds, Build{ID: buildsResp.Builds.All[i], Channel: ChannelStable})
+	}
+	return builds, nil
+}
+
+// Resolve finds the Purpur jar of a version and build. The latest version and build
+// are used if they are "latest" or empty. Purpur publishes an MD5 checksum for each build.
+func (p purpurProvider) Resolve(version, build string) (*Download, error) {
+	// If version is "latest", get the latest version
+	if version == VersionLatest {
+		versions, err := p.Versions()
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no Purpur versions found")
+		}
+		version = versions[0].ID
+	}
+
+	// Use the latest build unless a specific one was requested
+	if build == "" || build == VersionLatest {
+		buildsResp, err := getPurpurBuilds(version)
+		if err != nil {
+			return nil, err
+		}
+		if buildsResp.Builds.Latest == "" {
+			return nil, fmt.Errorf("no Purpur builds found for version %s", version)
+		}
+		build = buildsResp.Builds.Latest
+	}
+
+	var buildResp PurpurBuildResponse
+	if err := getJSON(fmt.Sprintf("%s/%s/%s", PurpurBaseURL, version, build), &buildResp); err != nil {
+		return nil, fmt.Errorf("failed to get Purpur build details: %w", err)
+	}
+	if buildResp.Result != "" && buildResp.Result != "SUCCESS" {
+		return nil, fmt.Errorf("build %s of Purpur %s did not succeed", build, version)
+	}
+
+	return &Download{
+		Provider: ProviderPurpur,
+		Version:  version,
+		Build:    build,
+		URL:      fmt.Sprintf("%s/%s/%s/download", PurpurBaseURL, version, build),
+		FileName: fmt.Sprintf("purpur-%s-%s.jar", version, build),
+		Checksum: Checksum{Algorithm: ChecksumMD5, Value: buildResp.MD5},
+	}, nil
+}
+
+// PostInstall does nothing for Purpur
+func (purpurProvider) PostInstall(serverPath string, download *Download, javaPath string) (*Launch, error) {
+	return nil, nil
+}
+
+// getPurpurBuilds gets the builds of a Purpur version
+func getPurpurBuilds(version string) (*PurpurBuildsResponse, error) {
+	var buildsResp PurpurBuildsResponse
+	if err := getJSON(fmt.Sprintf("%s/%s", PurpurBaseURL, version), &buildsResp); err != nil {
+		return nil, fmt.Errorf("failed to get Purpur builds: %w", err)
+	}
+	return &buildsResp, nil
+}
@@ -0,0 +1,173 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// QuiltMetaURL is the base URL of the Quilt meta API
+const QuiltMetaURL = "https://meta.quiltmc.org/v3"
+
+// QuiltServerLauncher is the jar the Quilt installer creates to launch the server
+const QuiltServerLauncher = "quilt-server-launch.jar"
+
+// QuiltGameVersion represents a game version in the Quilt meta API
+type QuiltGameVersion struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+// QuiltLoaderVersion represents a loader version in the Quilt meta API
+type QuiltLoaderVersion struct {
+	Build   int    `json:"build"`
+	Maven   string `json:"maven"`
+	Version string `json:"version"`
+}
+
+// QuiltInstallerVersion represents an installer version in the Quilt meta API
+type QuiltInstallerVersion struct {
+	URL     string `json:"url"`
+	Maven   string `json:"maven"`
+	Version string `json:"version"`
+}
+
+// quiltProvider provides Quilt servers, which are set up by the Quilt installer. The build
+// of a Quilt server is its loader version.
+type quiltProvider struct{}
+
+func init() {
+	Register(quiltProvider{})
+}
+
+// Name returns the server type of Quilt
+func (quiltProvider) Name() string {
+	return ProviderQuilt
+}
+
+// Description returns the display name of Quilt
+func (quiltProvider) Description() string {
+	return "Quilt"
+}
+
+// Proxy reports that Quilt servers are not proxies
+func (quiltProvider) Proxy() bool {
+	return false
+}
+
+// AddonDir returns the directory Quilt servers load mods from
+func (quiltProvider) AddonDir() string {
+	return "mods"
+}
+
+// Versions lists the game versions supported by Quilt
+func (quiltProvider) Versions() ([]Version, error) {
+	var gameVersions []QuiltGameVersion
+	if err := getJSON(QuiltMetaURL+"/versions/game", &gameVersions); err != nil {
+		return nil, fmt.Errorf("failed to get Quilt game versions: %w", err)
+	}
+
+	// The meta API lists the newest version first
+	versions := make([]Version, 0, len(gameVersions))
+	for _, v := range gameVersions {
+		channel := ChannelStable
+		if !v.Stable {
+			channel = ChannelSnapshot
+		}
+		versions = append(versions, Version{ID: v.Version, Channel: channel})
+	}
+	return versions, nil
+}
+
+// Builds lists the loader versions available for a game version. Beta loaders are experimental.
+func (quiltProvider) Builds(version string) ([]Build, error) {
+	var loaders []struct {
+		Loader QuiltLoaderVersion `json:"loader"`
+	}
+	if err := getJSON(fmt.Sprintf("%s/versions/loader/%s", QuiltMetaURL, version), &loaders); err != nil {
+		return nil, fmt.Errorf("failed to get Quilt loader versions: %w", err)
+	}
+
+	builds := make([]Build, 0, len(loaders))
+	for _, l := range loaders {
+		channel := ChannelStable
+		if strings.Contains(l.Loader.Version, "-") {
+			channel = ChannelExperimental
+		}
+		builds = append(builds, Build{ID: l.Loader.Version, Channel: channel})
+	}
+	return builds, nil
+}
+
+// Resolve finds the newest Quilt installer for a game version and loader version. The newest
+// stable game version and loader are used if they are "latest" or empty.
+func (p quiltProvider) Resolve(version, build string) (*Download, error) {
+	// If version is "latest", get the newest stable game version
+	if version == VersionLatest {
+		versions, err := p.Versions()
+		if err != nil {
+			return nil, err
+		}
+		version = ""
+		for _, v := range versions {
+			if v.Channel == ChannelStable {
+				version = v.ID
+				break
+			}
+		}
+		if version == "" {
+			return nil, fmt.Errorf("no Quilt game versions found")
+		}
+	}
+
+	// If no loader version is provided, use the newest stable loader for the game version
+	if build == "" || build == VersionLatest {
+		builds, err := p.Builds(version)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range builds {
+			if b.Channel == ChannelStable {
+				build = b.ID
+				break
+			}
+		}
+		if build == "" || build == VersionLatest {
+			return nil, fmt.Errorf("no stable Quilt loader found for Minecraft %s", version)
+		}
+	}
+
+	var installers []QuiltInstallerVersion
+	if err := getJSON(QuiltMetaURL+"/versions/installer", &installers); err != nil {
+		return nil, fmt.Errorf("failed to get Quilt installer versions: %w", err)
+	}
+	if len(installers) == 0 {
+		return nil, fmt.Errorf("no Quilt installer versions found")
+	}
+	installer := installers[0]
+
+	return newMavenDownload(ProviderQuilt, version, build, installer.URL, filepath.Base(installer.URL))
+}
+
+// PostInstall runs the Quilt installer, which downloads the vanilla server and the loader
+// libraries and creates the server launcher jar
+func (p quiltProvider) PostInstall(serverPath string, download *Download, javaPath string) (*Launch, error) {
+	absPath, err := filepath.Abs(serverPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve server path: %w", err)
+	}
+
+	if err := runInstaller(serverPath, download, javaPath, p.Description(),
+		"install", "server", download.Version, download.Build, "--download-server", "--install-dir="+absPath); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(serverPath, QuiltServerLauncher)); err != nil {
+		return nil, fmt.Errorf("the Quilt installer did not create %s: %w", QuiltServerLauncher, err)
+	}
+
+	fmt.Printf("Quilt server for Minecraft %s with loader %s is ready\n", download.Version, download.Build)
+	fmt.Println("Note: Most mods will also require you to install Quilted Fabric API (QFAPI) into the mods folder")
+
+	return &Launch{Jar: QuiltServerLauncher}, nil
+}
@@ -0,0 +1,100 @@
+package downloader
+
+import (
+	"fmt"
+)
+
+// vanillaProvider provides the vanilla server jars published by Mojang
+type vanillaProvider struct{}
+
+func init() {
+	Register(vanillaProvider{})
+}
+
+// Name returns the server type of vanilla servers
+func (vanillaProvider) Name() string {
+	return ProviderVanilla
+}
+
+// Description returns the display name of the vanilla server
+func (vanillaProvider) Description() string {
+	return "Minecraft"
+}
+
+// Proxy reports that vanilla servers are not proxies
+func (vanillaProvider) Proxy() bool {
+	return false
+}
+
+// AddonDir reports that vanilla servers load neither plugins nor mods
+func (vanillaProvider) AddonDir() string {
+	return ""
+}
+
+// Versions lists the versions in Mojang's launcher version manifest
+func (vanillaProvider) Versions() ([]Version, error) {
+	manifest, err := GetMojangManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	// The manifest lists the newest version first
+	versions := make([]Version, 0, len(manifest.Versions))
+	for _, v := range manifest.Versions {
+		channel := ChannelOld
+		switch v.Type {
+		case "release":
+			channel = ChannelStable
+		case "snapshot":
+			channel = ChannelSnapshot
+		}
+		versions = append(versions, Version{ID: v.ID, Channel: channel, ReleaseTime: v.ReleaseTime})
+	}
+	return versions, nil
+}
+
+// Builds returns no builds, since each vanilla version has exactly one server jar
+func (vanillaProvider) Builds(version string) ([]Build, error) {
+	return nil, nil
+}
+
+// Resolve finds the server jar of a version, which may also be "latest" or "latest-snapshot".
+// The jar is verified against the SHA-1 published by Mojang.
+func (vanillaProvider) Resolve(version, build string) (*Download, error) {
+	if build != "" {
+		return nil, fmt.Errorf("vanilla servers have no builds")
+	}
+
+	// Look the version up in the launcher version manifest
+	manifest, err := GetMojangManifest()
+	if err != nil {
+		return nil, err
+	}
+	mojangVersion, err := manifest.Resolve(version)
+	if err != nil {
+		return nil, err
+	}
+
+	// The per-version JSON lists the server download, which very old versions do not have
+	details, err := GetMojangVersionDetails(mojangVersion)
+	if err != nil {
+		return nil, err
+	}
+	server := details.Downloads.Server
+	if server == nil {
+		return nil, fmt.Errorf("no server download is available for Minecraft %s", mojangVersion.ID)
+	}
+
+	return &Download{
+		Provider: ProviderVanilla,
+		Version:  mojangVersion.ID,
+		URL:      server.URL,
+		FileName: "minecraft_server." + mojangVersion.ID + ".jar",
+		Checksum: Checksum{Algorithm: ChecksumSHA1, Value: server.SHA1},
+	}, nil
+}
+
+// PostInstall does nothing for vanilla servers
+func (vanillaProvider) PostInstall(serverPath string, download *Download, javaPath string) (*Launch, error) {
+	return nil, nil
+}
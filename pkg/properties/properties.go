@@ -0,0 +1,407 @@
+// Package properties reads and edits Java .properties files such as server.properties.
+// Files are kept line by line, so comments, blank lines, the order of the keys and the
+// formatting of untouched entries are written back exactly as they were read.
+package properties
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/store"
+)
+
+// Properties is a parsed .properties file
+type Properties struct {
+	lines []line
+}
+
+// line is a comment, a blank line or an entry. Entries can span several lines through
+// line continuations.
+type line struct {
+	// raw is the text as it appears in the file, including line terminators
+	raw string
+	// entry is set for key/value lines
+	entry bool
+	key   string
+	value string
+	// prefix is the indentation, raw key and separator of an entry, kept when its value changes
+	prefix string
+	// eol is the line terminator of the entry, empty for a last line without one
+	eol string
+}
+
+// New returns an empty properties file
+func New() *Properties {
+	return &Properties{}
+}
+
+// Load reads a properties file
+func Load(path string) (*Properties, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse parses the contents of a properties file following the rules of Java's
+// Properties.load: keys end at the first unescaped '=', ':' or whitespace, a line ending in
+// an odd number of backslashes continues on the next line, and backslash and \uXXXX escapes
+// are decoded
+func Parse(data []byte) (*Properties, error) {
+	p := &Properties{}
+	natural := splitLines(string(data))
+
+	for i := 0; i < len(natural); i++ {
+		text, eol := natural[i].text, natural[i].eol
+		trimmed := strings.TrimLeft(text, " \t\f")
+
+		// Blank lines and comments are kept as they are, comments never continue
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+			p.lines = append(p.lines, line{raw: text + eol})
+			continue
+		}
+
+		// Join continuation lines into one logical line
+		raw := text + eol
+		logical := trimmed
+		for endsWithContinuation(logical) && i+1 < len(natural) {
+			i++
+			logical = logical[:len(logical)-1] + strings.TrimLeft(natural[i].text, " \t\f")
+			raw += natural[i].text + natural[i].eol
+			eol = natural[i].eol
+		}
+		if endsWithContinuation(logical) {
+			// A continuation on the last line of the file continues into nothing
+			logical = logical[:len(logical)-1]
+		}
+
+		keyEnd, valueStart := splitEntry(logical)
+		key, err := unescape(logical[:keyEnd])
+		if err != nil {
+			return nil, err
+		}
+		value, err := unescape(logical[valueStart:])
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := text[:len(text)-len(trimmed)] + logical[:valueStart]
+		if valueStart == keyEnd {
+			prefix += "="
+		}
+		p.lines = append(p.lines, line{raw: raw, entry: true, key: key, value: value, prefix: prefix, eol: eol})
+	}
+
+	return p, nil
+}
+
+// Save writes the properties file. The file is replaced atomically, so a crash while
+// saving leaves either the old or the new contents rather than an empty file.
+func (p *Properties) Save(path string) error {
+	return store.WriteFile(path, p.Bytes(), 0644)
+}
+
+// Bytes returns the contents of the properties file
+func (p *Properties) Bytes() []byte {
+	var b strings.Builder
+	for _, l := range p.lines {
+		b.WriteString(l.raw)
+	}
+	return []byte(b.String())
+}
+
+// Keys returns the keys in the order they appear in the file
+func (p *Properties) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, l := range p.lines {
+		if l.entry && !seen[l.key] {
+			seen[l.key] = true
+			keys = append(keys, l.key)
+		}
+	}
+	return keys
+}
+
+// Get returns the value of a key. Like Java, the last entry wins if a key appears more than once.
+func (p *Properties) Get(key string) (string, bool) {
+	if i := p.find(key); i >= 0 {
+		return p.lines[i].value, true
+	}
+	return "", false
+}
+
+// Set sets the value of a key. An existing entry keeps its place and formatting,
+// a new key is appended to the end of the file.
+func (p *Properties) Set(key, value string) {
+	if i := p.find(key); i >= 0 {
+		l := &p.lines[i]
+		if l.value == value {
+			return
+		}
+		l.value = value
+		l.raw = l.prefix + escape(value, false) + l.eol
+		return
+	}
+
+	p.terminate()
+	eol := "\n"
+	prefix := escape(key, true) + "="
+	p.lines = append(p.lines, line{
+		raw:    prefix + escape(value, false) + eol,
+		entry:  true,
+		key:    key,
+		value:  value,
+		prefix: prefix,
+		eol:    eol,
+	})
+}
+
+// Unset removes every entry of a key and reports whether there was one
+func (p *Properties) Unset(key string) bool {
+	removed := false
+	lines := p.lines[:0]
+	for _, l := range p.lines {
+		if l.entry && l.key == key {
+			removed = true
+			continue
+		}
+		lines = append(lines, l)
+	}
+	p.lines = lines
+	return removed
+}
+
+// Comment appends a comment line to the end of the file
+func (p *Properties) Comment(text string) {
+	p.terminate()
+	p.lines = append(p.lines, line{raw: "# " + text + "\n"})
+}
+
+// Int returns the value of a key as an integer, or def if the key is not set
+func (p *Properties) Int(key string, def int) (int, error) {
+	value, ok := p.Get(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return def, fmt.Errorf("%s is not an integer: %q", key, value)
+	}
+	return i, nil
+}
+
+// Bool returns the value of a key as a boolean, or def if the key is not set
+func (p *Properties) Bool(key string, def bool) (bool, error) {
+	value, ok := p.Get(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return def, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return def, fmt.Errorf("%s is not true or false: %q", key, value)
+}
+
+// SetInt sets the value of a key to an integer
+func (p *Properties) SetInt(key string, value int) {
+	p.Set(key, strconv.Itoa(value))
+}
+
+// SetBool sets the value of a key to a boolean
+func (p *Properties) SetBool(key string, value bool) {
+	p.Set(key, strconv.FormatBool(value))
+}
+
+// terminate makes sure the last line is terminated before another line is appended
+func (p *Properties) terminate() {
+	if n := len(p.lines); n > 0 {
+		last := &p.lines[n-1]
+		if !strings.HasSuffix(last.raw, "\n") && !strings.HasSuffix(last.raw, "\r") {
+			last.raw += "\n"
+			if last.entry {
+				last.eol = "\n"
+			}
+		}
+	}
+}
+
+// find returns the index of the last entry of a key, or -1
+func (p *Properties) find(key string) int {
+	for i := len(p.lines) - 1; i >= 0; i-- {
+		if p.lines[i].entry && p.lines[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// naturalLine is a line of the file with its terminator split off
+type naturalLine struct {
+	text string
+	eol  string
+}
+
+// splitLines splits text into lines ending in \n, \r or \r\n
+func splitLines(s string) []naturalLine {
+	var lines []naturalLine
+	for len(s) > 0 {
+		i := strings.IndexAny(s, "\r\n")
+		if i < 0 {
+			lines = append(lines, naturalLine{text: s})
+			break
+		}
+		eolLen := 1
+		if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+			eolLen = 2
+		}
+		lines = append(lines, naturalLine{text: s[:i], eol: s[i : i+eolLen]})
+		s = s[i+eolLen:]
+	}
+	return lines
+}
+
+// endsWithContinuation reports whether a line ends in an odd number of backslashes
+func endsWithContinuation(s string) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitEntry finds the end of the key and the start of the value in a logical line
+func splitEntry(s string) (keyEnd, valueStart int) {
+	keyEnd = len(s)
+	valueStart = len(s)
+	hasSeparator := false
+	backslash := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if backslash {
+			backslash = false
+			continue
+		}
+		if c == '\\' {
+			backslash = true
+			continue
+		}
+		if c == '=' || c == ':' {
+			keyEnd, valueStart = i, i+1
+			hasSeparator = true
+			break
+		}
+		if c == ' ' || c == '\t' || c == '\f' {
+			keyEnd, valueStart = i, i+1
+			break
+		}
+	}
+
+	// Skip the whitespace around the separator, and the separator after whitespace
+	for valueStart < len(s) {
+		c := s[valueStart]
+		if c != ' ' && c != '\t' && c != '\f' {
+			if hasSeparator || (c != '=' && c != ':') {
+				break
+			}
+			hasSeparator = true
+		}
+		valueStart++
+	}
+	return keyEnd, valueStart
+}
+
+// unescape decodes the backslash escapes of a key or value
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	var pending []uint16
+	flush := func() {
+		if len(pending) > 0 {
+			b.WriteString(string(utf16.Decode(pending)))
+			pending = pending[:0]
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 >= len(s) {
+			flush()
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch c = s[i]; c {
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\uxxxx escape in %q", s)
+			}
+			code, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uxxxx escape in %q", s)
+			}
+			// Surrogate pairs are written as two escapes, so decode them together
+			pending = append(pending, uint16(code))
+			i += 4
+			continue
+		case 't':
+			c = '\t'
+		case 'n':
+			c = '\n'
+		case 'r':
+			c = '\r'
+		case 'f':
+			c = '\f'
+		}
+		flush()
+		b.WriteByte(c)
+	}
+	flush()
+
+	return b.String(), nil
+}
+
+// escape encodes a key or value the way Java's Properties.store does. Spaces are escaped
+// everywhere in keys but only at the start of values. Characters outside ASCII are written
+// as they are, since Minecraft reads server.properties as UTF-8.
+func escape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,215 @@
+package properties
+
+import "testing"
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		values map[string]string
+	}{
+		{
+			name:   "comments and blank lines",
+			input:  "#Minecraft server properties\n! other comment\n\n   # indented comment\nmotd=A Minecraft Server\n",
+			values: map[string]string{"motd": "A Minecraft Server"},
+		},
+		{
+			name:   "CRLF line endings",
+			input:  "a=1\r\nb = 2\r\n\r\n# c\r\n",
+			values: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:   "CR line endings",
+			input:  "a=1\rb=2\r",
+			values: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:   "missing trailing newline",
+			input:  "a=1\nb=2",
+			values: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:   "continuation lines",
+			input:  "motd = first \\\n    second \\\r\n\tthird\nnext=1\n",
+			values: map[string]string{"motd": "first second third", "next": "1"},
+		},
+		{
+			name:   "escaped backslash does not continue",
+			input:  "path=C:\\\\\nnext=1\n",
+			values: map[string]string{"path": "C:\\", "next": "1"},
+		},
+		{
+			name:   "continuation on the last line",
+			input:  "a=b\\",
+			values: map[string]string{"a": "b"},
+		},
+		{
+			name:   "separators and whitespace",
+			input:  "a:b\nc d\ne   =   f\n  g=h\ni \t: j\nflag\n",
+			values: map[string]string{"a": "b", "c": "d", "e": "f", "g": "h", "i": "j", "flag": ""},
+		},
+		{
+			name:   "escapes in keys and values",
+			input:  "k\\ ey=\\u00e9\\uD83D\\uDE00\na\\=b=c\\:d\nlevel-type=minecraft\\:normal\nlead=\\ x\n",
+			values: map[string]string{"k ey": "é😀", "a=b": "c:d", "level-type": "minecraft:normal", "lead": " x"},
+		},
+		{
+			name:   "empty file",
+			input:  "",
+			values: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse returned error: %v", err)
+			}
+			if got := string(p.Bytes()); got != tt.input {
+				t.Errorf("Bytes() = %q, want %q", got, tt.input)
+			}
+			for key, want := range tt.values {
+				got, ok := p.Get(key)
+				if !ok || got != want {
+					t.Errorf("Get(%q) = %q, %v, want %q", key, got, ok, want)
+				}
+			}
+			if len(p.Keys()) != len(tt.values) {
+				t.Errorf("Keys() = %q, want %d keys", p.Keys(), len(tt.values))
+			}
+		})
+	}
+}
+
+func TestParseMalformedEscape(t *testing.T) {
+	if _, err := Parse([]byte("a=\\u12\n")); err == nil {
+		t.Error("Parse accepted a malformed \\u escape")
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		key   string
+		value string
+		want  string
+	}{
+		{"keeps indentation and separator", "  motd : old\nb=2\n", "motd", "new", "  motd : new\nb=2\n"},
+		{"keeps CRLF", "a=1\r\nb=2\r\n", "a", "3", "a=3\r\nb=2\r\n"},
+		{"keeps the last line unterminated", "a=1\nb=2", "b", "3", "a=1\nb=3"},
+		{"same value changes nothing", "a =  1\n", "a", "1", "a =  1\n"},
+		{"replaces continuation lines", "k=a\\\n  b\nz=1\n", "k", "c", "k=c\nz=1\n"},
+		{"key without separator", "flag\n", "flag", "x", "flag=x\n"},
+		{"changes the last duplicate", "a=1\na=2\n", "a", "3", "a=1\na=3\n"},
+		{"escapes the value", "a=1\n", "a", " x=y", "a=\\ x\\=y\n"},
+		{"keeps leading spaces", "a=1\n", "a", "  x", "a=\\  x\n"},
+		{"keeps non-BMP characters", "a=1\n", "a", "😀", "a=😀\n"},
+		{"appends a new key", "# c\na=1\n", "b", "2", "# c\na=1\nb=2\n"},
+		{"appends after an unterminated line", "a=1", "b", "2", "a=1\nb=2\n"},
+		{"appends an escaped key", "", "a b", "c", "a\\ b=c\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse returned error: %v", err)
+			}
+			p.Set(tt.key, tt.value)
+			if got := string(p.Bytes()); got != tt.want {
+				t.Errorf("Bytes() = %q, want %q", got, tt.want)
+			}
+			if got, _ := p.Get(tt.key); got != tt.value {
+				t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.value)
+			}
+
+			// The written file reads back the same value
+			reparsed, err := Parse(p.Bytes())
+			if err != nil {
+				t.Fatalf("Parse of the result returned error: %v", err)
+			}
+			if got, _ := reparsed.Get(tt.key); got != tt.value {
+				t.Errorf("reparsed Get(%q) = %q, want %q", tt.key, got, tt.value)
+			}
+		})
+	}
+}
+
+func TestUnset(t *testing.T) {
+	p, err := Parse([]byte("a=1\nb=2\na=3\n"))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !p.Unset("a") {
+		t.Error("Unset(a) = false, want true")
+	}
+	if p.Unset("missing") {
+		t.Error("Unset(missing) = true, want false")
+	}
+	if got, want := string(p.Bytes()), "b=2\n"; got != want {
+		t.Errorf("Bytes() = %q, want %q", got, want)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		input string
+		key   bool
+		want  string
+	}{
+		{" leading", false, "\\ leading"},
+		{"  two", false, "\\  two"},
+		{"inner space", false, "inner space"},
+		{"inner space", true, "inner\\ space"},
+		{"a=b:c#d!e", false, "a\\=b\\:c\\#d\\!e"},
+		{"back\\slash", false, "back\\\\slash"},
+		{"tab\tnl\ncr\rff\f", false, "tab\\tnl\\ncr\\rff\\f"},
+		{"é😀", false, "é😀"},
+		{"", false, ""},
+	}
+
+	for _, tt := range tests {
+		if got := escape(tt.input, tt.key); got != tt.want {
+			t.Errorf("escape(%q, %v) = %q, want %q", tt.input, tt.key, got, tt.want)
+		}
+		if got, err := unescape(tt.want); err != nil || got != tt.input {
+			t.Errorf("unescape(%q) = %q, %v, want %q", tt.want, got, err, tt.input)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"\\ lead", " lead"},
+		{"\\=\\:\\#\\!", "=:#!"},
+		{"\\u00e9", "é"},
+		{"\\u00E9t\\u00e9", "été"},
+		{"\\uD83D\\uDE00", "😀"},
+		{"a\\uD83D\\uDE00b", "a😀b"},
+		{"\\t\\n\\r\\f", "\t\n\r\f"},
+		{"\\q", "q"},
+	}
+
+	for _, tt := range tests {
+		got, err := unescape(tt.input)
+		if err != nil {
+			t.Errorf("unescape(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("unescape(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"\\u12", "\\uzzzz"} {
+		if _, err := unescape(input); err == nil {
+			t.Errorf("unescape(%q) accepted a malformed escape", input)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package properties
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Types of the values in server.properties
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeEnum   = "enum"
+)
+
+// Key describes a key of a vanilla server.properties file
+type Key struct {
+	Type string
+	// Min and Max limit the values of integer keys
+	Min int
+	Max int
+	// Values lists the allowed values of enum keys
+	Values []string
+	// Live returns the console command that applies a value to a running server,
+	// for the few keys that can be changed without a restart
+	Live func(value string) string
+}
+
+// Schema lists the keys of a vanilla Minecraft server.properties file. Mods, plugins and
+// older or newer versions can use other keys, so keys that are not listed are not errors.
+var Schema = map[string]Key{
+	"accepts-transfers":                 {Type: TypeBool},
+	"allow-flight":                      {Type: TypeBool},
+	"allow-nether":                      {Type: TypeBool},
+	"broadcast-console-to-ops":          {Type: TypeBool},
+	"broadcast-rcon-to-ops":             {Type: TypeBool},
+	"bug-report-link":                   {Type: TypeString},
+	"difficulty":                        {Type: TypeEnum, Values: []string{"peaceful", "easy", "normal", "hard"}, Live: command("difficulty %s")},
+	"enable-command-block":              {Type: TypeBool},
+	"enable-jmx-monitoring":             {Type: TypeBool},
+	"enable-query":                      {Type: TypeBool},
+	"enable-rcon":                       {Type: TypeBool},
+	"enable-status":                     {Type: TypeBool},
+	"enforce-secure-profile":            {Type: TypeBool},
+	"enforce-whitelist":                 {Type: TypeBool},
+	"entity-broadcast-range-percentage": {Type: TypeInt, Min: 10, Max: 1000},
+	"force-gamemode":                    {Type: TypeBool},
+	"function-permission-level":         {Type: TypeInt, Min: 1, Max: 4},
+	"gamemode":                          {Type: TypeEnum, Values: []string{"survival", "creative", "adventure", "spectator"}, Live: command("defaultgamemode %s")},
+	"generate-structures":               {Type: TypeBool},
+	"generator-settings":                {Type: TypeString},
+	"hardcore":                          {Type: TypeBool},
+	"hide-online-players":               {Type: TypeBool},
+	"initial-disabled-packs":            {Type: TypeString},
+	"initial-enabled-packs":             {Type: TypeString},
+	"level-name":                        {Type: TypeString},
+	"level-seed":                        {Type: TypeString},
+	"level-type":                        {Type: TypeString},
+	"log-ips":                           {Type: TypeBool},
+	"max-chained-neighbor-updates":      {Type: TypeInt, Min: math.MinInt32, Max: math.MaxInt32},
+	"max-players":                       {Type: TypeInt, Min: 0, Max: math.MaxInt32},
+	"max-tick-time":                     {Type: TypeInt, Min: -1, Max: math.MaxInt32},
+	"max-world-size":                    {Type: TypeInt, Min: 1, Max: 29999984},
+	"motd":                              {Type: TypeString},
+	"network-compression-threshold":     {Type: TypeInt, Min: -1, Max: math.MaxInt32},
+	"online-mode":                       {Type: TypeBool},
+	"op-permission-level":               {Type: TypeInt, Min: 0, Max: 4},
+	"pause-when-empty-seconds":          {Type: TypeInt, Min: math.MinInt32, Max: math.MaxInt32},
+	"player-idle-timeout":               {Type: TypeInt, Min: 0, Max: math.MaxInt32, Live: command("setidletimeout %s")},
+	"prevent-proxy-connections":         {Type: TypeBool},
+	"pvp":                               {Type: TypeBool},
+	"query.port":                        {Type: TypeInt, Min: 1, Max: 65535},
+	"rate-limit":                        {Type: TypeInt, Min: 0, Max: math.MaxInt32},
+	"rcon.password":                     {Type: TypeString},
+	"rcon.port":                         {Type: TypeInt, Min: 1, Max: 65535},
+	"region-file-compression":           {Type: TypeEnum, Values: []string{"deflate", "lz4", "none"}},
+	"require-resource-pack":             {Type: TypeBool},
+	"resource-pack":                     {Type: TypeString},
+	"resource-pack-id":                  {Type: TypeString},
+	"resource-pack-prompt":              {Type: TypeString},
+	"resource-pack-sha1":                {Type: TypeString},
+	"server-ip":                         {Type: TypeString},
+	"server-port":                       {Type: TypeInt, Min: 1, Max: 65535},
+	"simulation-distance":               {Type: TypeInt, Min: 3, Max: 32},
+	"spawn-monsters":                    {Type: TypeBool},
+	"spawn-protection":                  {Type: TypeInt, Min: 0, Max: math.MaxInt32},
+	"sync-chunk-writes":                 {Type: TypeBool},
+	"text-filtering-config":             {Type: TypeString},
+	"text-filtering-version":            {Type: TypeInt, Min: 0, Max: math.MaxInt32},
+	"use-native-transport":              {Type: TypeBool},
+	"view-distance":                     {Type: TypeInt, Min: 3, Max: 32},
+	"white-list":                        {Type: TypeBool, Live: whitelistCommand},
+}
+
+// Validate checks a value against the schema and returns it the way the server expects it,
+// such as booleans and enum values in lower case. Keys that are not in the schema are
+// returned unchanged.
+func Validate(key, value string) (string, error) {
+	k, ok := Schema[key]
+	if !ok {
+		return value, nil
+	}
+
+	switch k.Type {
+	case TypeInt:
+		i, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("%s must be an integer, not %q", key, value)
+		}
+		if i < k.Min || i > k.Max {
+			return "", fmt.Errorf("%s must be between %d and %d, not %d", key, k.Min, k.Max, i)
+		}
+		return strconv.Itoa(i), nil
+	case TypeBool:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true":
+			return "true", nil
+		case "false":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%s must be true or false, not %q", key, value)
+	case TypeEnum:
+		lower := strings.ToLower(strings.TrimSpace(value))
+		for _, v := range k.Values {
+			if lower == v {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s, not %q", key, strings.Join(k.Values, ", "), value)
+	}
+	return value, nil
+}
+
+// Known reports whether a key is in the schema
+func Known(key string) bool {
+	_, ok := Schema[key]
+	return ok
+}
+
+// LiveCommand returns the console command that applies a value to a running server, or an
+// empty string if the key only takes effect after a restart
+func LiveCommand(key, value string) string {
+	k, ok := Schema[key]
+	if !ok || k.Live == nil {
+		return ""
+	}
+	return k.Live(value)
+}
+
+// command returns a Live function for a command that takes the value as its argument
+func command(format string) func(string) string {
+	return func(value string) string {
+		return fmt.Sprintf(format, value)
+	}
+}
+
+// whitelistCommand turns the whitelist on or off
+func whitelistCommand(value string) string {
+	if value == "true" {
+		return "whitelist on"
+	}
+	return "whitelist off"
+}
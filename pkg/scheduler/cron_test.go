@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// setValues returns the values set in a parsed cron field
+func setValues(field []bool) []int {
+	var values []int
+	for v, set := range field {
+		if set {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// fullRange returns the values from min to max
+func fullRange(min, max int) []int {
+	var values []int
+	for v := min; v <= max; v++ {
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		minutes []int
+		hours   []int
+		days    []int
+		months  []int
+		weekday []int
+	}{
+		{"0 3 * * *", []int{0}, []int{3}, fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+		{"*/15 * * * *", []int{0, 15, 30, 45}, fullRange(0, 23), fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+		{"1-5 8-17 * * *", []int{1, 2, 3, 4, 5}, fullRange(8, 17), fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+		{"10-20/5 */6 * * *", []int{10, 15, 20}, []int{0, 6, 12, 18}, fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+		{"5/20 0 * * *", []int{5, 25, 45}, []int{0}, fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+		{"0,30 0 1,15,28-31 * *", []int{0, 30}, []int{0}, []int{1, 15, 28, 29, 30, 31}, fullRange(1, 12), fullRange(0, 7)},
+		{"0 0 1 */3 *", []int{0}, []int{0}, []int{1}, []int{1, 4, 7, 10}, fullRange(0, 7)},
+		{"0 0 * * 1-5", []int{0}, []int{0}, fullRange(1, 31), fullRange(1, 12), []int{1, 2, 3, 4, 5}},
+		{"0 0 * * 7", []int{0}, []int{0}, fullRange(1, 31), fullRange(1, 12), []int{0, 7}},
+		{"0 0 * * 5-7", []int{0}, []int{0}, fullRange(1, 31), fullRange(1, 12), []int{0, 5, 6, 7}},
+		{"  0  0  *  *  *  ", []int{0}, []int{0}, fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+		{"@yearly", []int{0}, []int{0}, []int{1}, []int{1}, fullRange(0, 7)},
+		{"@annually", []int{0}, []int{0}, []int{1}, []int{1}, fullRange(0, 7)},
+		{"@monthly", []int{0}, []int{0}, []int{1}, fullRange(1, 12), fullRange(0, 7)},
+		{"@weekly", []int{0}, []int{0}, fullRange(1, 31), fullRange(1, 12), []int{0}},
+		{"@daily", []int{0}, []int{0}, fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+		{"@midnight", []int{0}, []int{0}, fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+		{"@HOURLY", []int{0}, fullRange(0, 23), fullRange(1, 31), fullRange(1, 12), fullRange(0, 7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron returned error: %v", err)
+			}
+			fields := []struct {
+				name string
+				got  []bool
+				want []int
+			}{
+				{"minutes", schedule.minutes, tt.minutes},
+				{"hours", schedule.hours, tt.hours},
+				{"days", schedule.days, tt.days},
+				{"months", schedule.months, tt.months},
+				{"weekday", schedule.weekday, tt.weekday},
+			}
+			for _, f := range fields {
+				if got := setValues(f.got); !reflect.DeepEqual(got, f.want) {
+					t.Errorf("%s = %v, want %v", f.name, got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@sometimes",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"-1 * * * *",
+		"5-1 * * * *",
+		"1-x * * * *",
+		"*/0 * * * *",
+		"*/-5 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+	}
+
+	for _, expr := range tests {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) returned no error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.Parse("2006-01-02 15:04:05", value)
+		if err != nil {
+			t.Fatalf("invalid time %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	// 2025-01-01 is a Wednesday
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		{"later the same day", "0 3 * * *", "2025-01-01 02:59:30", "2025-01-01 03:00:00"},
+		{"strictly after the current minute", "0 3 * * *", "2025-01-01 03:00:00", "2025-01-02 03:00:00"},
+		{"within the current minute", "0 3 * * *", "2025-01-01 03:00:59", "2025-01-02 03:00:00"},
+		{"step", "*/15 * * * *", "2025-01-01 10:07:00", "2025-01-01 10:15:00"},
+		{"step into the next hour", "*/15 * * * *", "2025-01-01 10:50:00", "2025-01-01 11:00:00"},
+		{"list", "0 6,18 * * *", "2025-01-01 07:00:00", "2025-01-01 18:00:00"},
+		{"range of weekdays", "30 9 * * 1-5", "2025-01-03 10:00:00", "2025-01-06 09:30:00"},
+		{"next month", "0 0 1 * *", "2025-01-15 00:00:00", "2025-02-01 00:00:00"},
+		{"skips short months", "0 0 31 * *", "2025-02-01 00:00:00", "2025-03-31 00:00:00"},
+		{"month step", "0 0 1 */3 *", "2025-02-10 00:00:00", "2025-04-01 00:00:00"},
+		{"next year", "0 0 1 1 *", "2025-01-01 00:00:00", "2026-01-01 00:00:00"},
+		{"leap day", "0 0 29 2 *", "2025-01-01 00:00:00", "2028-02-29 00:00:00"},
+		{"sunday as 0", "0 0 * * 0", "2025-01-01 00:00:00", "2025-01-05 00:00:00"},
+		{"sunday as 7", "0 0 * * 7", "2025-01-01 00:00:00", "2025-01-05 00:00:00"},
+		{"weekly macro", "@weekly", "2025-01-01 00:00:00", "2025-01-05 00:00:00"},
+		{"day of month only", "0 12 15 * *", "2025-01-01 00:00:00", "2025-01-15 12:00:00"},
+		{"day of week only", "0 12 * * 1", "2025-01-01 00:00:00", "2025-01-06 12:00:00"},
+		{"either day field, weekday first", "0 12 15 * 1", "2025-01-01 00:00:00", "2025-01-06 12:00:00"},
+		{"either day field, day of month first", "0 12 15 * 1", "2025-01-13 12:00:00", "2025-01-15 12:00:00"},
+		{"either day field, weekday after day of month", "0 12 15 * 1", "2025-01-15 12:00:00", "2025-01-20 12:00:00"},
+		{"both day fields restricted to a range", "0 0 1 * 6-7", "2025-01-01 00:00:00", "2025-01-04 00:00:00"},
+		{"never", "0 0 30 2 *", "2025-01-01 00:00:00", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) returned error: %v", tt.expr, err)
+			}
+			var want time.Time
+			if tt.want != "" {
+				want = at(tt.want)
+			}
+			if got := schedule.Next(at(tt.from)); !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, want)
+			}
+		})
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	schedule, err := ParseCron("0 12 15 * 1")
+	if err != nil {
+		t.Fatalf("ParseCron returned error: %v", err)
+	}
+
+	tests := []struct {
+		time time.Time
+		want bool
+	}{
+		// The 15th, a Wednesday, matches the day of month
+		{time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC), true},
+		// The 6th, a Monday, matches the day of week
+		{time.Date(2025, 1, 6, 12, 0, 30, 0, time.UTC), true},
+		// The 7th is neither
+		{time.Date(2025, 1, 7, 12, 0, 0, 0, time.UTC), false},
+		// The right day at the wrong time
+		{time.Date(2025, 1, 15, 12, 1, 0, 0, time.UTC), false},
+		{time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		if got := schedule.Matches(tt.time); got != tt.want {
+			t.Errorf("Matches(%s) = %v, want %v", tt.time, got, tt.want)
+		}
+	}
+}
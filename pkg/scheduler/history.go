@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/store"
+)
+
+// maxHistoryEntries is the number of runs kept in the history file
+const maxHistoryEntries = 1000
+
+// HistoryEntry represents a single execution of a scheduled task
+type HistoryEntry struct {
+	Server     string    `json:"server"`
+	Schedule   string    `json:"schedule"`
+	Action     string    `json:"action"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// getHistoryFilePath returns the path to the file where the run history is stored
+func getHistoryFilePath() string {
+	return filepath.Join(config.StateDir(), "schedule_history.json")
+}
+
+// LoadHistory loads the run history, oldest first.
+// If serverName is not empty, only runs of that server are returned.
+func LoadHistory(serverName string) ([]HistoryEntry, error) {
+	var runs []HistoryEntry
+	err := store.LoadJSON(getHistoryFilePath(), &runs)
+	if os.IsNotExist(err) {
+		return []HistoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedule history: %w", err)
+	}
+
+	if serverName == "" {
+		return runs, nil
+	}
+
+	filtered := make([]HistoryEntry, 0, len(runs))
+	for _, run := range runs {
+		if run.Server == serverName {
+			filtered = append(filtered, run)
+		}
+	}
+	return filtered, nil
+}
+
+// appendHistory adds a run to the history file, dropping the oldest entries beyond the limit
+func appendHistory(run HistoryEntry) error {
+	// Hold the lock while the file is read and written, so entries recorded by other
+	// mcsrvr processes at the same time are kept
+	lock, err := store.Lock(getHistoryFilePath())
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	runs, err := LoadHistory("")
+	if err != nil {
+		return err
+	}
+
+	runs = append(runs, run)
+	if len(runs) > maxHistoryEntries {
+		runs = runs[len(runs)-maxHistoryEntries:]
+	}
+
+	if err := store.SaveJSON(getHistoryFilePath(), runs); err != nil {
+		return fmt.Errorf("failed to save schedule history: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/backup"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
+)
+
+// Actions that can be scheduled
+const (
+	ActionBackup  = "backup"
+	ActionPrune   = "prune"
+	ActionRestart = "restart"
+	ActionCommand = "command"
+)
+
+// Validate checks that a schedule is well-formed before it is saved
+func Validate(schedule config.Schedule) error {
+	if schedule.Name == "" {
+		return fmt.Errorf("schedule name is required")
+	}
+	if _, err := ParseCron(schedule.Cron); err != nil {
+		return err
+	}
+
+	switch schedule.Action {
+	case ActionBackup, ActionPrune, ActionRestart:
+	case ActionCommand:
+		if schedule.Command == "" {
+			return fmt.Errorf("the command action requires a command")
+		}
+	default:
+		return fmt.Errorf("unknown action '%s'. Supported actions: backup, prune, restart, command", schedule.Action)
+	}
+
+	if _, err := parseWarnings(schedule.Warnings); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseWarnings parses the countdown warnings of a restart, longest first
+func parseWarnings(warnings []string) ([]time.Duration, error) {
+	durations := make([]time.Duration, 0, len(warnings))
+	for _, warning := range warnings {
+		d, err := time.ParseDuration(warning)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid warning duration: %s", warning)
+		}
+		durations = append(durations, d)
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] > durations[j] })
+	return durations, nil
+}
+
+// daemon runs scheduled tasks
+type daemon struct {
+	// serverLocks serialise the tasks of each server, so tasks of one server never run at
+	// the same time while the tasks of other servers are not held up
+	serverLocks   map[string]*sync.Mutex
+	serverLocksMu sync.Mutex
+	// running holds the tasks currently executing so a slow task is not started twice
+	running   map[string]bool
+	runningMu sync.Mutex
+	wg        sync.WaitGroup
+}
+
+// Run checks the schedules of all servers at the start of every minute and runs the tasks
+// that are due, until stop is closed. Tasks that are still running are waited for.
+func Run(stop <-chan struct{}) error {
+	d := &daemon{running: make(map[string]bool), serverLocks: make(map[string]*sync.Mutex)}
+
+	logf("Scheduler daemon started")
+	for {
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-stop:
+			logf("Scheduler daemon stopping, waiting for running tasks...")
+			d.wg.Wait()
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+
+		d.tick(next)
+	}
+}
+
+// tick starts every task that is due in the given minute
+func (d *daemon) tick(t time.Time) {
+	servers, err := config.ListServers()
+	if err != nil {
+		logf("Error: Failed to load servers: %v", err)
+		return
+	}
+
+	for _, srv := range servers {
+		for _, schedule := range srv.Schedules {
+			cron, err := ParseCron(schedule.Cron)
+			if err != nil {
+				logf("Error: Schedule '%s' of server '%s' is invalid: %v", schedule.Name, srv.Name, err)
+				continue
+			}
+			if !cron.Matches(t) {
+				continue
+			}
+
+			key := srv.Name + "/" + schedule.Name
+			d.runningMu.Lock()
+			if d.running[key] {
+				d.runningMu.Unlock()
+				logf("Skipping schedule '%s' of server '%s': previous run is still in progress", schedule.Name, srv.Name)
+				continue
+			}
+			d.running[key] = true
+			d.runningMu.Unlock()
+
+			d.wg.Add(1)
+			go func(serverName string, schedule config.Schedule) {
+				defer d.wg.Done()
+				defer func() {
+					d.runningMu.Lock()
+					delete(d.running, key)
+					d.runningMu.Unlock()
+				}()
+				d.execute(serverName, schedule)
+			}(srv.Name, schedule)
+		}
+	}
+}
+
+// execute runs a single task and records the result in the history
+func (d *daemon) execute(serverName string, schedule config.Schedule) {
+	run := HistoryEntry{
+		Server:    serverName,
+		Schedule:  schedule.Name,
+		Action:    schedule.Action,
+		StartedAt: time.Now(),
+	}
+
+	logf("Running schedule '%s' (%s) of server '%s'", schedule.Name, schedule.Action, serverName)
+	err := d.runAction(serverName, schedule)
+
+	run.FinishedAt = time.Now()
+	run.Success = err == nil
+	if err != nil {
+		run.Error = err.Error()
+		logf("Error: Schedule '%s' of server '%s' failed: %v", schedule.Name, serverName, err)
+	} else {
+		logf("Schedule '%s' of server '%s' finished successfully", schedule.Name, serverName)
+	}
+
+	if err := appendHistory(run); err != nil {
+		logf("Warning: Failed to save schedule history: %v", err)
+	}
+}
+
+// runAction performs the action of a schedule
+func (d *daemon) runAction(serverName string, schedule config.Schedule) error {
+	switch schedule.Action {
+	case ActionBackup:
+		repoPath, err := backup.DefaultRepository()
+		if err != nil {
+			return err
+		}
+		return d.withServer(serverName, func() error {
+			return server.CreateBackup(serverName, repoPath)
+		})
+	case ActionPrune:
+		repoPath, err := backup.DefaultRepository()
+		if err != nil {
+			return err
+		}
+		return d.withServer(serverName, func() error {
+			return server.PruneBackups(repoPath, serverName, false)
+		})
+	case ActionCommand:
+		return d.withServer(serverName, func() error {
+			return server.ExecuteCommand(serverName, schedule.Command)
+		})
+	case ActionRestart:
+		return d.restart(serverName, schedule)
+	default:
+		return fmt.Errorf("unknown action '%s'", schedule.Action)
+	}
+}
+
+// restart announces the restart in-game at each warning and then restarts the server
+func (d *daemon) restart(serverName string, schedule config.Schedule) error {
+	warnings, err := parseWarnings(schedule.Warnings)
+	if err != nil {
+		return err
+	}
+
+	for i, warning := range warnings {
+		message := fmt.Sprintf("say Server restarting in %s", server.FormatCountdown(warning))
+		err := d.withServer(serverName, func() error {
+			return server.ExecuteCommand(serverName, message)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to announce restart: %w", err)
+		}
+
+		// Sleep until the next warning, or until the restart after the last one
+		wait := warning
+		if i+1 < len(warnings) {
+			wait = warning - warnings[i+1]
+		}
+		time.Sleep(wait)
+	}
+
+	return d.withServer(serverName, func() error {
+		return server.RestartServer(serverName)
+	})
+}
+
+// withServer runs fn with exclusive access to a server, reloading the active servers
+// first so changes made by other mcsrvr commands are picked up
+func (d *daemon) withServer(serverName string, fn func() error) error {
+	lock := d.serverLock(serverName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := process.ReloadActiveServers(); err != nil {
+		return fmt.Errorf("failed to load active servers: %w", err)
+	}
+	return fn()
+}
+
+// serverLock returns the lock that serialises the tasks of a server
+func (d *daemon) serverLock(serverName string) *sync.Mutex {
+	d.serverLocksMu.Lock()
+	defer d.serverLocksMu.Unlock()
+
+	lock, exists := d.serverLocks[serverName]
+	if !exists {
+		lock = &sync.Mutex{}
+		d.serverLocks[serverName] = lock
+	}
+	return lock
+}
+
+// logf prints a timestamped daemon message
+func logf(format string, args ...interface{}) {
+	fmt.Printf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,162 @@
+package backup
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Backups created before the chunk store was introduced are single zip archives.
+// They can still be restored, but new backups are always written to a repository.
+
+// ManifestName is the name of the manifest entry stored inside every backup archive
+const ManifestName = "mcsrvr-manifest.json"
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	Server    string          `json:"server"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Files     []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry describes a single file stored in a backup archive
+type ManifestEntry struct {
+	Path   string      `json:"path"`
+	Size   int64       `json:"size"`
+	Mode   os.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+}
+
+// extractArchive unpacks archivePath into targetDir and verifies every file against the manifest
+func extractArchive(archivePath, targetDir string) (*Manifest, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	// Index the archive entries and load the manifest
+	entries := make(map[string]*zip.File)
+	var manifest *Manifest
+	for _, f := range zr.File {
+		if f.Name == ManifestName {
+			manifest, err = readManifest(f)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		entries[f.Name] = f
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("archive does not contain a manifest: %s", archivePath)
+	}
+
+	// Recreate directories first, including empty ones
+	for name, f := range entries {
+		if !f.FileInfo().IsDir() {
+			continue
+		}
+		dirPath, err := safeJoin(targetDir, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dirPath, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", name, err)
+		}
+	}
+
+	// Extract every file listed in the manifest and check its hash
+	var mismatches []string
+	for _, entry := range manifest.Files {
+		f, exists := entries[entry.Path]
+		if !exists {
+			return nil, fmt.Errorf("file listed in manifest is missing from archive: %s", entry.Path)
+		}
+
+		destPath, err := safeJoin(targetDir, entry.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		sum, err := extractFile(f, destPath, entry.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", entry.Path, err)
+		}
+		if sum != entry.SHA256 {
+			mismatches = append(mismatches, entry.Path)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return manifest, fmt.Errorf("%d file(s) failed hash verification: %s", len(mismatches), strings.Join(mismatches, ", "))
+	}
+
+	return manifest, nil
+}
+
+// extractFile writes a single archive entry to destPath and returns the SHA-256 of the written data
+func extractFile(f *zip.File, destPath string, mode os.FileMode) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return "", err
+	}
+
+	in, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	if mode == 0 {
+		mode = 0644
+	}
+	out, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hash), in); err != nil {
+		out.Close()
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
+	// Keep the original modification time
+	os.Chtimes(destPath, f.Modified, f.Modified)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readManifest decodes the manifest entry of an archive
+func readManifest(f *zip.File) (*Manifest, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer r.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// safeJoin joins an archive entry name onto dir and rejects names that would escape it
+func safeJoin(dir, name string) (string, error) {
+	relPath := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if !filepath.IsLocal(relPath) {
+		return "", fmt.Errorf("archive entry has an unsafe path: %s", name)
+	}
+	return filepath.Join(dir, relPath), nil
+}
@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeArchive writes a zip archive in the format of older versions of mcsrvr
+func writeArchive(t *testing.T, path string, files map[string][]byte, manifest Manifest, extra ...string) {
+	t.Helper()
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	for _, name := range extra {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := zw.Create(ManifestName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(w).Encode(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	files := map[string][]byte{
+		"server.properties": []byte("motd=A Minecraft Server\n"),
+		"world/level.dat":   randomBytes(5, 5000),
+	}
+	manifest := Manifest{Server: "test"}
+	for name, data := range files {
+		sum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, ManifestEntry{Path: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	dir := t.TempDir()
+
+	// An intact archive is restored through RestoreBackup and verified
+	archive := filepath.Join(dir, "test_2025-01-01_00-00-00.zip")
+	writeArchive(t, archive, files, manifest, "world/playerdata/")
+	restored := t.TempDir()
+	if err := RestoreBackup(dir, archive, restored); err != nil {
+		t.Fatalf("RestoreBackup of a zip archive returned error: %v", err)
+	}
+	checkTree(t, restored, files)
+	checkTree(t, restored, map[string][]byte{"world/playerdata": nil})
+
+	// A file that does not match the manifest is reported
+	tampered := map[string][]byte{"server.properties": []byte("motd=Tampered\n"), "world/level.dat": files["world/level.dat"]}
+	archive = filepath.Join(dir, "tampered.zip")
+	writeArchive(t, archive, tampered, manifest)
+	_, err := extractArchive(archive, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "failed hash verification: server.properties") {
+		t.Errorf("extractArchive of a tampered archive = %v, want a hash verification error", err)
+	}
+
+	// Entries that would be written outside the target directory are rejected
+	unsafe := Manifest{Server: "test", Files: []ManifestEntry{{Path: "../evil.txt"}}}
+	archive = filepath.Join(dir, "unsafe.zip")
+	writeArchive(t, archive, map[string][]byte{"../evil.txt": []byte("evil")}, unsafe)
+	target := t.TempDir()
+	if _, err := extractArchive(archive, target); err == nil {
+		t.Error("extractArchive accepted an entry outside the target directory")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(target), "evil.txt")); !os.IsNotExist(err) {
+		t.Error("extractArchive wrote a file outside the target directory")
+	}
+}
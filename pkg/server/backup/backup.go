@@ -6,103 +6,233 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/util"
 )
 
-// CreateBackup creates a backup of a Minecraft server
-func CreateBackup(serverName, backupPath string) error {
-	// Create the backup directory if it doesn't exist
-	if err := os.MkdirAll(backupPath, 0755); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
+// BackupInfo summarises a single backup snapshot
+type BackupInfo struct {
+	ID        string
+	Server    string
+	CreatedAt time.Time
+	Files     int
+	// LogicalSize is the total size of all files in the snapshot
+	LogicalSize int64
+	// StoredSize is the compressed size of all distinct chunks the snapshot references
+	StoredSize int64
+	// UniqueSize is the compressed size of the chunks no other snapshot references,
+	// i.e. the space that would be freed by deleting the snapshot
+	UniqueSize int64
+}
+
+// DefaultRepository returns the path of the default backup repository
+func DefaultRepository() (string, error) {
+	dataDir := config.DataDir()
+	if dataDir == "" {
+		return "", fmt.Errorf("the mcsrvr directories are not initialized")
+	}
+
+	return filepath.Join(dataDir, "backups"), nil
+}
+
+// CreateBackup creates a new snapshot of a Minecraft server in the backup repository
+func CreateBackup(serverName, serverPath, repoPath string) error {
+	// Check if the server directory exists
+	if _, err := os.Stat(serverPath); os.IsNotExist(err) {
+		return fmt.Errorf("server directory does not exist: %s", serverPath)
 	}
 
+	repo, err := OpenRepository(repoPath)
+	if err != nil {
+		return err
+	}
+
+	// Keep prunes from removing chunks this backup reuses until its snapshot is saved
+	lock, err := repo.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	// Create a timestamp for the backup
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	backupName := fmt.Sprintf("%s_%s", serverName, timestamp)
-	backupDir := filepath.Join(backupPath, backupName)
+	createdAt := time.Now()
+	snapshot := &Snapshot{
+		ID:        fmt.Sprintf("%s_%s", serverName, createdAt.Format("2006-01-02_15-04-05")),
+		Server:    serverName,
+		CreatedAt: createdAt,
+	}
+	if _, err := os.Stat(repo.snapshotPath(snapshot.ID)); err == nil {
+		return fmt.Errorf("backup '%s' already exists", snapshot.ID)
+	}
 
-	// Create the backup directory
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
+	// Unchanged files are taken over from the most recent snapshot of this server
+	var previous *Snapshot
+	snapshots, err := repo.Snapshots(serverName)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) > 0 {
+		previous = snapshots[len(snapshots)-1]
 	}
 
-	// Copy the server files to the backup directory
-	// This is a placeholder implementation
-	// In a real implementation, we would need to copy all the server files
-	fmt.Printf("Creating backup of server '%s' to '%s'...\n", serverName, backupDir)
+	// Never back up the repository into itself
+	absRepoPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve backup repository: %w", err)
+	}
 
-	// For now, we'll just print a message
-	fmt.Println("Backup created successfully")
+	fmt.Printf("Creating backup '%s' of server '%s' in '%s'...\n", snapshot.ID, serverName, repoPath)
 
-	return nil
-}
+	stats, err := repo.storeTree(snapshot, serverPath, previous, []string{absRepoPath})
+	if err != nil {
+		return err
+	}
 
-// RestoreBackup restores a backup of a Minecraft server
-func RestoreBackup(backupPath, restorePath string) error {
-	// Check if the backup directory exists
-	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
-		return fmt.Errorf("backup directory does not exist: %s", backupPath)
+	if err := repo.saveSnapshot(snapshot); err != nil {
+		return err
 	}
 
+	fmt.Printf("Backup created successfully: %d files (%d unchanged), %s logical, %d new chunks (%s stored)\n",
+		stats.files, stats.reused, util.FormatSize(stats.bytes), stats.newChunks, util.FormatSize(stats.newStored))
+
+	return nil
+}
+
+// RestoreBackup restores a backup of a Minecraft server.
+// The backup can be a snapshot ID in the repository, a path to a snapshot file,
+// or a path to a zip archive created by earlier versions of mcsrvr.
+func RestoreBackup(repoPath, backupName, restorePath string) error {
 	// Create the restore directory if it doesn't exist
 	if err := os.MkdirAll(restorePath, 0755); err != nil {
 		return fmt.Errorf("failed to create restore directory: %w", err)
 	}
 
-	// Copy the backup files to the restore directory
-	// This is a placeholder implementation
-	// In a real implementation, we would need to copy all the backup files
-	fmt.Printf("Restoring backup from '%s' to '%s'...\n", backupPath, restorePath)
+	// Restore legacy zip archives directly
+	if strings.HasSuffix(backupName, ".zip") {
+		if _, err := os.Stat(backupName); err == nil {
+			fmt.Printf("Restoring backup from '%s' to '%s'...\n", backupName, restorePath)
+
+			manifest, err := extractArchive(backupName, restorePath)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Backup restored successfully: %d files verified\n", len(manifest.Files))
+			return nil
+		}
+	}
+
+	// A path to a snapshot file selects both the repository and the snapshot
+	id := backupName
+	if strings.HasSuffix(backupName, snapshotExt) {
+		if _, err := os.Stat(backupName); err == nil {
+			repoPath = filepath.Dir(filepath.Dir(backupName))
+			id = strings.TrimSuffix(filepath.Base(backupName), snapshotExt)
+		}
+	}
 
-	// For now, we'll just print a message
-	fmt.Println("Backup restored successfully")
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+		return fmt.Errorf("backup repository does not exist: %s", repoPath)
+	}
+	repo := &Repository{Path: repoPath}
+
+	// Keep prunes from removing the snapshot while it is restored
+	lock, err := repo.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	snapshot, err := repo.LoadSnapshot(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restoring backup '%s' to '%s'...\n", snapshot.ID, restorePath)
+
+	// Reassemble every file from the chunk store and verify its hash
+	if err := repo.restoreTree(snapshot, restorePath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Backup restored successfully: %d files verified\n", len(snapshot.Files))
 
 	return nil
 }
 
-// ListBackups lists all backups for a server
-func ListBackups(serverName string) ([]string, error) {
-	// Get the user's home directory
-	homeDir, err := os.UserHomeDir()
+// ListBackups lists all backups in a repository, optionally only those of a specific server
+func ListBackups(repoPath, serverName string) ([]BackupInfo, error) {
+	// Check if the repository exists
+	if _, err := os.Stat(filepath.Join(repoPath, snapshotsDirName)); os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	repo := &Repository{Path: repoPath}
+
+	// Load every snapshot, chunk sharing is counted across all servers
+	snapshots, err := repo.Snapshots("")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+		return nil, err
 	}
 
-	// Determine the backups directory
-	backupsDir := filepath.Join(homeDir, ".mcsrvr", "backups")
+	return summarize(repo, snapshots, serverName), nil
+}
 
-	// Check if the backups directory exists
-	if _, err := os.Stat(backupsDir); os.IsNotExist(err) {
-		return []string{}, nil
+// summarize computes the sizes of each snapshot. Chunks are counted as unique only if
+// no other snapshot in the given set references them.
+func summarize(repo *Repository, snapshots []*Snapshot, serverName string) []BackupInfo {
+	references := make(map[string]int)
+	for _, snapshot := range snapshots {
+		for hash := range snapshotChunks(snapshot) {
+			references[hash]++
+		}
 	}
 
-	// List all backups for the server
-	var backups []string
+	sizes := make(map[string]int64)
+	chunkSize := func(hash string) int64 {
+		if size, exists := sizes[hash]; exists {
+			return size
+		}
+		size := repo.chunkSize(hash)
+		sizes[hash] = size
+		return size
+	}
 
-	// If no server name is provided, list all backups
-	if serverName == "" {
-		entries, err := os.ReadDir(backupsDir)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read backups directory: %w", err)
+	backups := []BackupInfo{}
+	for _, snapshot := range snapshots {
+		if serverName != "" && snapshot.Server != serverName {
+			continue
 		}
 
-		for _, entry := range entries {
-			if entry.IsDir() {
-				backups = append(backups, entry.Name())
-			}
+		info := BackupInfo{
+			ID:        snapshot.ID,
+			Server:    snapshot.Server,
+			CreatedAt: snapshot.CreatedAt,
+			Files:     len(snapshot.Files),
 		}
-	} else {
-		// List backups for the specific server
-		entries, err := os.ReadDir(backupsDir)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read backups directory: %w", err)
+		for _, entry := range snapshot.Files {
+			info.LogicalSize += entry.Size
 		}
-
-		for _, entry := range entries {
-			if entry.IsDir() && strings.HasPrefix(entry.Name(), serverName+"_") {
-				backups = append(backups, entry.Name())
+		for hash := range snapshotChunks(snapshot) {
+			size := chunkSize(hash)
+			info.StoredSize += size
+			if references[hash] == 1 {
+				info.UniqueSize += size
 			}
 		}
+		backups = append(backups, info)
 	}
 
-	return backups, nil
+	return backups
+}
+
+// snapshotChunks returns the set of distinct chunks referenced by a snapshot
+func snapshotChunks(snapshot *Snapshot) map[string]struct{} {
+	chunks := make(map[string]struct{})
+	for _, entry := range snapshot.Files {
+		for _, hash := range entry.Chunks {
+			chunks[hash] = struct{}{}
+		}
+	}
+	return chunks
 }
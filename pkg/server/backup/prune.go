@@ -0,0 +1,282 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/util"
+)
+
+// gcGracePeriod protects unreferenced chunks from garbage collection while they are this new
+const gcGracePeriod = time.Hour
+
+// RetentionPolicy decides which snapshots of a server are kept when pruning
+type RetentionPolicy struct {
+	// KeepLast keeps the newest N snapshots
+	KeepLast int
+	// KeepDaily keeps the newest snapshot of each of the last N days that have snapshots
+	KeepDaily int
+	// KeepWeekly keeps the newest snapshot of each of the last N weeks that have snapshots
+	KeepWeekly int
+	// MaxTotalSize drops the oldest kept snapshots until the stored size of the
+	// server's backups fits. The newest snapshot is always kept. 0 means no limit.
+	MaxTotalSize int64
+}
+
+// IsEmpty reports whether the policy has no rules. Nothing is pruned without rules.
+func (p RetentionPolicy) IsEmpty() bool {
+	return p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0 && p.MaxTotalSize <= 0
+}
+
+// Prune deletes the snapshots of a server that fall outside the retention policy and removes
+// chunks no longer referenced by any snapshot. With dryRun set nothing is deleted.
+func Prune(repoPath, serverName string, policy RetentionPolicy, dryRun bool) error {
+	if policy.IsEmpty() {
+		fmt.Printf("No retention policy configured for server '%s', keeping all backups\n", serverName)
+		return nil
+	}
+
+	// Check if the repository exists
+	if _, err := os.Stat(filepath.Join(repoPath, snapshotsDirName)); os.IsNotExist(err) {
+		fmt.Printf("No backups found for server '%s'.\n", serverName)
+		return nil
+	}
+	repo := &Repository{Path: repoPath}
+
+	// Wait for running backups, whose chunks are not referenced by a snapshot yet
+	lock, err := repo.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	all, err := repo.Snapshots("")
+	if err != nil {
+		return err
+	}
+
+	var snapshots []*Snapshot
+	for _, snapshot := range all {
+		if snapshot.Server == serverName {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	kept := repo.selectKept(snapshots, policy)
+
+	// Split the repository into the snapshots that stay and the ones to remove
+	var remaining, removed []*Snapshot
+	for _, snapshot := range all {
+		if snapshot.Server == serverName && !kept[snapshot.ID] {
+			removed = append(removed, snapshot)
+		} else {
+			remaining = append(remaining, snapshot)
+		}
+	}
+
+	if len(removed) == 0 {
+		fmt.Printf("All %d backups of server '%s' are within the retention policy\n", len(snapshots), serverName)
+		return nil
+	}
+
+	// Chunks that no remaining snapshot references can be freed
+	referenced := make(map[string]struct{})
+	for _, snapshot := range remaining {
+		for hash := range snapshotChunks(snapshot) {
+			referenced[hash] = struct{}{}
+		}
+	}
+
+	verb := "Removing"
+	if dryRun {
+		verb = "Would remove"
+	}
+	for _, snapshot := range removed {
+		fmt.Printf("%s backup '%s'\n", verb, snapshot.ID)
+	}
+
+	if dryRun {
+		// Count the chunks the same way the garbage collection picks them
+		unusedChunks, freedSize, err := repo.collectGarbage(referenced, true)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dry run: %d of %d backups of server '%s' and %d unused chunks would be removed, freeing %s\n",
+			len(removed), len(snapshots), serverName, unusedChunks, util.FormatSize(freedSize))
+		return nil
+	}
+
+	// Delete the snapshot indexes first so a failure never leaves snapshots with missing chunks
+	for _, snapshot := range removed {
+		if err := os.Remove(repo.snapshotPath(snapshot.ID)); err != nil {
+			return fmt.Errorf("failed to remove backup '%s': %w", snapshot.ID, err)
+		}
+	}
+
+	removedChunks, freedSize, err := repo.collectGarbage(referenced, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d of %d backups of server '%s' and %d unused chunks, freeing %s\n",
+		len(removed), len(snapshots), serverName, removedChunks, util.FormatSize(freedSize))
+
+	return nil
+}
+
+// selectKept applies a retention policy to the snapshots of one server (oldest first)
+// and returns the IDs of the snapshots to keep
+func (r *Repository) selectKept(snapshots []*Snapshot, policy RetentionPolicy) map[string]bool {
+	kept := make(map[string]bool)
+
+	// Walk from newest to oldest
+	newestFirst := make([]*Snapshot, len(snapshots))
+	for i, snapshot := range snapshots {
+		newestFirst[len(snapshots)-1-i] = snapshot
+	}
+
+	if policy.KeepLast <= 0 && policy.KeepDaily <= 0 && policy.KeepWeekly <= 0 {
+		// Only a size limit is set, so everything is a candidate
+		for _, snapshot := range newestFirst {
+			kept[snapshot.ID] = true
+		}
+	}
+
+	for i, snapshot := range newestFirst {
+		if i < policy.KeepLast {
+			kept[snapshot.ID] = true
+		}
+	}
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for _, snapshot := range newestFirst {
+		created := snapshot.CreatedAt.Local()
+
+		day := created.Format("2006-01-02")
+		if !days[day] && len(days) < policy.KeepDaily {
+			days[day] = true
+			kept[snapshot.ID] = true
+		}
+
+		year, week := created.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[weekKey] && len(weeks) < policy.KeepWeekly {
+			weeks[weekKey] = true
+			kept[snapshot.ID] = true
+		}
+	}
+
+	if policy.MaxTotalSize <= 0 {
+		return kept
+	}
+
+	// Drop the oldest kept snapshots until the server's backups fit, but keep the newest one
+	for {
+		var keptSnapshots []*Snapshot
+		for _, snapshot := range snapshots {
+			if kept[snapshot.ID] {
+				keptSnapshots = append(keptSnapshots, snapshot)
+			}
+		}
+		if len(keptSnapshots) <= 1 || r.storedSize(keptSnapshots) <= policy.MaxTotalSize {
+			break
+		}
+		delete(kept, keptSnapshots[0].ID)
+	}
+
+	return kept
+}
+
+// storedSize returns the compressed size of all distinct chunks referenced by the snapshots
+func (r *Repository) storedSize(snapshots []*Snapshot) int64 {
+	chunks := make(map[string]struct{})
+	for _, snapshot := range snapshots {
+		for hash := range snapshotChunks(snapshot) {
+			chunks[hash] = struct{}{}
+		}
+	}
+
+	var size int64
+	for hash := range chunks {
+		size += r.chunkSize(hash)
+	}
+	return size
+}
+
+// collectGarbage removes every chunk that is not in the referenced set and returns
+// how many chunks were removed and how many bytes were freed. With dryRun set it only
+// counts the chunks it would remove.
+func (r *Repository) collectGarbage(referenced map[string]struct{}, dryRun bool) (int, int64, error) {
+	removed := 0
+	var freed int64
+	err := filepath.Walk(filepath.Join(r.Path, chunksDirName), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Leave directories and chunks that are still being written alone
+		if info.IsDir() || strings.HasSuffix(info.Name(), ".part") {
+			return nil
+		}
+		if _, exists := referenced[info.Name()]; exists {
+			return nil
+		}
+		// Recent chunks may belong to a backup by an older version of mcsrvr, which did not
+		// lock the repository
+		if time.Since(info.ModTime()) < gcGracePeriod {
+			return nil
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		removed++
+		freed += info.Size()
+		return nil
+	})
+	if err != nil {
+		return removed, freed, fmt.Errorf("failed to remove unused chunks: %w", err)
+	}
+
+	return removed, freed, nil
+}
+
+// ParseSize parses a size such as "500M", "50G" or "1T" into bytes.
+// A plain number is interpreted as bytes.
+func ParseSize(size string) (int64, error) {
+	original := size
+	size = strings.TrimSpace(strings.ToUpper(size))
+	if size == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	size = strings.TrimSuffix(strings.TrimSuffix(size, "B"), "I")
+	if size != "" {
+		switch size[len(size)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			size = size[:len(size)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(size), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size: %q", original)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
@@ -0,0 +1,216 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// snapshotAt returns a snapshot created at a local time such as "2025-01-01 10:00",
+// made of the given chunks. Local time is used because retention counts local days and weeks.
+func snapshotAt(t *testing.T, id, created string, chunks ...string) *Snapshot {
+	t.Helper()
+	createdAt, err := time.ParseInLocation("2006-01-02 15:04", created, time.Local)
+	if err != nil {
+		t.Fatalf("invalid time %q: %v", created, err)
+	}
+	return &Snapshot{
+		ID:        id,
+		Server:    "test",
+		CreatedAt: createdAt,
+		Files:     []FileEntry{{Path: id, Chunks: chunks}},
+	}
+}
+
+// keptIDs returns the sorted IDs of the kept snapshots
+func keptIDs(kept map[string]bool) []string {
+	ids := []string{}
+	for id, keep := range kept {
+		if keep {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSelectKept(t *testing.T) {
+	// Oldest first. 2025-01-01 is a Wednesday, so the snapshots fall into the ISO weeks
+	// 2025-W01 (a, b, c), 2025-W02 (d, e) and 2025-W03 (f, g, h).
+	snapshots := []*Snapshot{
+		snapshotAt(t, "a", "2025-01-01 10:00"),
+		snapshotAt(t, "b", "2025-01-01 22:00"),
+		snapshotAt(t, "c", "2025-01-04 09:00"),
+		snapshotAt(t, "d", "2025-01-07 09:00"),
+		snapshotAt(t, "e", "2025-01-07 21:00"),
+		snapshotAt(t, "f", "2025-01-13 08:00"),
+		snapshotAt(t, "g", "2025-01-14 08:00"),
+		snapshotAt(t, "h", "2025-01-14 20:00"),
+	}
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{"keep last", RetentionPolicy{KeepLast: 3}, []string{"f", "g", "h"}},
+		{"keep last more than exist", RetentionPolicy{KeepLast: 20}, []string{"a", "b", "c", "d", "e", "f", "g", "h"}},
+		{"keep daily", RetentionPolicy{KeepDaily: 3}, []string{"e", "f", "h"}},
+		{"keep daily more than exist", RetentionPolicy{KeepDaily: 10}, []string{"b", "c", "e", "f", "h"}},
+		{"keep weekly", RetentionPolicy{KeepWeekly: 2}, []string{"e", "h"}},
+		{"keep weekly more than exist", RetentionPolicy{KeepWeekly: 5}, []string{"c", "e", "h"}},
+		{"rules are combined", RetentionPolicy{KeepLast: 1, KeepDaily: 2, KeepWeekly: 3}, []string{"c", "e", "f", "h"}},
+		{"overlapping rules", RetentionPolicy{KeepLast: 2, KeepDaily: 1}, []string{"g", "h"}},
+	}
+
+	repo := &Repository{Path: t.TempDir()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keptIDs(repo.selectKept(snapshots, tt.policy))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectKeptISOWeeks(t *testing.T) {
+	// Monday 2024-12-30 and Sunday 2025-01-05 are both in ISO week 2025-W01,
+	// while Sunday 2024-12-29 is in 2024-W52
+	snapshots := []*Snapshot{
+		snapshotAt(t, "w52", "2024-12-29 12:00"),
+		snapshotAt(t, "w01-monday", "2024-12-30 12:00"),
+		snapshotAt(t, "w01-sunday", "2025-01-05 12:00"),
+	}
+
+	repo := &Repository{Path: t.TempDir()}
+	got := keptIDs(repo.selectKept(snapshots, RetentionPolicy{KeepWeekly: 2}))
+	if want := []string{"w01-sunday", "w52"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+}
+
+func TestSelectKeptMaxTotalSize(t *testing.T) {
+	repo := &Repository{Path: t.TempDir()}
+
+	// Each snapshot has a chunk of its own and shares one with the others
+	chunkSizes := map[string]int{"aa01": 100, "bb01": 100, "cc01": 100, "shared": 50}
+	for hash, size := range chunkSizes {
+		path := repo.chunkPath(hash)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots := []*Snapshot{
+		snapshotAt(t, "s1", "2025-01-01 00:00", "aa01", "shared"),
+		snapshotAt(t, "s2", "2025-01-02 00:00", "bb01", "shared"),
+		snapshotAt(t, "s3", "2025-01-03 00:00", "cc01", "shared"),
+	}
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{"everything fits", RetentionPolicy{MaxTotalSize: 350}, []string{"s1", "s2", "s3"}},
+		{"shared chunks are counted once", RetentionPolicy{MaxTotalSize: 349}, []string{"s2", "s3"}},
+		{"oldest dropped first", RetentionPolicy{MaxTotalSize: 250}, []string{"s2", "s3"}},
+		{"down to the newest", RetentionPolicy{MaxTotalSize: 249}, []string{"s3"}},
+		{"newest is always kept", RetentionPolicy{MaxTotalSize: 1}, []string{"s3"}},
+		{"size limit does not add snapshots", RetentionPolicy{KeepLast: 2, MaxTotalSize: 1000}, []string{"s2", "s3"}},
+		{"size limit applies to kept snapshots", RetentionPolicy{KeepLast: 3, MaxTotalSize: 200}, []string{"s3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keptIDs(repo.selectKept(snapshots, tt.policy))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"", 0},
+		{"1024", 1024},
+		{"500M", 500 << 20},
+		{"500MB", 500 << 20},
+		{"500MiB", 500 << 20},
+		{"1.5G", 3 << 29},
+		{"50g", 50 << 30},
+		{"1T", 1 << 40},
+		{" 2K ", 2 << 10},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"abc", "-1G", "G"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("ParseSize(%q) returned no error", input)
+		}
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	repo := &Repository{Path: t.TempDir()}
+	old := time.Now().Add(-2 * gcGracePeriod)
+
+	// "kept" is referenced, "unused" can be collected and "recent" is within the grace period
+	chunks := []struct {
+		hash  string
+		size  int
+		mtime time.Time
+	}{
+		{"kept01", 10, old},
+		{"unused01", 20, old},
+		{"recent01", 40, time.Now()},
+	}
+	for _, chunk := range chunks {
+		path := repo.chunkPath(chunk.hash)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, chunk.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, chunk.mtime, chunk.mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	referenced := map[string]struct{}{"kept01": {}}
+
+	// The dry run promises exactly what the real run frees
+	for _, dryRun := range []bool{true, false} {
+		removed, freed, err := repo.collectGarbage(referenced, dryRun)
+		if err != nil {
+			t.Fatalf("collectGarbage(dryRun=%v) returned error: %v", dryRun, err)
+		}
+		if removed != 1 || freed != 20 {
+			t.Errorf("collectGarbage(dryRun=%v) = %d chunks, %d bytes, want 1 chunk, 20 bytes", dryRun, removed, freed)
+		}
+	}
+
+	for _, chunk := range chunks {
+		_, err := os.Stat(repo.chunkPath(chunk.hash))
+		if exists := err == nil; exists != (chunk.hash != "unused01") {
+			t.Errorf("chunk %s exists = %v after collection", chunk.hash, exists)
+		}
+	}
+}
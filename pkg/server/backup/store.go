@@ -0,0 +1,481 @@
+package backup
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/store"
+)
+
+const (
+	// chunksDirName is the repository directory holding the content-addressed chunks
+	chunksDirName = "chunks"
+	// snapshotsDirName is the repository directory holding one index file per snapshot
+	snapshotsDirName = "snapshots"
+	// snapshotExt is the file extension of snapshot index files
+	snapshotExt = ".json"
+	// lockName is the name of the repository lock, held while snapshots are created or pruned
+	lockName = "repository"
+
+	// regionChunkSize is the chunk size for region files. Minecraft rewrites region files
+	// in 4 KiB sectors, so small sector-aligned chunks keep unchanged parts deduplicated.
+	regionChunkSize = 64 * 1024
+	// defaultChunkSize is the chunk size for every other file
+	defaultChunkSize = 4 * 1024 * 1024
+)
+
+// Snapshot is the index of a single backup. The file contents live in the chunk store.
+type Snapshot struct {
+	ID        string      `json:"id"`
+	Server    string      `json:"server"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Dirs      []string    `json:"dirs,omitempty"`
+	Files     []FileEntry `json:"files"`
+}
+
+// FileEntry describes a single file of a snapshot and the chunks it is made of
+type FileEntry struct {
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+	SHA256  string      `json:"sha256"`
+	Chunks  []string    `json:"chunks"`
+}
+
+// Repository is a backup repository made of a chunk store and snapshot indexes
+type Repository struct {
+	Path string
+}
+
+// OpenRepository opens the backup repository at path, creating its layout if needed
+func OpenRepository(path string) (*Repository, error) {
+	for _, dir := range []string{path, filepath.Join(path, chunksDirName), filepath.Join(path, snapshotsDirName)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create backup repository: %w", err)
+		}
+	}
+	return &Repository{Path: path}, nil
+}
+
+// lock takes the exclusive lock of the repository. Backups reuse chunks that are already
+// in the store, so a prune running at the same time could otherwise remove chunks that a
+// snapshot being written refers to.
+func (r *Repository) lock() (*store.FileLock, error) {
+	lock, err := store.Lock(filepath.Join(r.Path, lockName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock backup repository: %w", err)
+	}
+	return lock, nil
+}
+
+// validChunkHash reports whether a chunk hash read from a snapshot is a SHA-256 in hex,
+// so a damaged snapshot cannot make chunkPath point elsewhere or slice a short hash
+func validChunkHash(hash string) bool {
+	if len(hash) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// validateSnapshotID checks that a snapshot ID, which may be typed by the user, names a
+// file in the snapshots directory and not a path outside it
+func validateSnapshotID(id string) error {
+	if id == "" || id == "." || id == ".." || filepath.Base(id) != id || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("invalid backup ID '%s'", id)
+	}
+	return nil
+}
+
+// chunkPath returns the path of a chunk, fanned out by the first two hex digits of its hash.
+// The hash must have been checked with validChunkHash.
+func (r *Repository) chunkPath(hash string) string {
+	return filepath.Join(r.Path, chunksDirName, hash[:2], hash)
+}
+
+// snapshotPath returns the path of a snapshot index file
+func (r *Repository) snapshotPath(id string) string {
+	return filepath.Join(r.Path, snapshotsDirName, id+snapshotExt)
+}
+
+// putChunk stores a chunk unless a chunk with the same hash already exists.
+// It returns the chunk hash and whether new data was written.
+func (r *Repository) putChunk(data []byte) (string, bool, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := r.chunkPath(hash)
+
+	// Identical data has already been stored
+	if _, err := os.Stat(path); err == nil {
+		return hash, false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", false, err
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", false, err
+	}
+	if err := zw.Close(); err != nil {
+		return "", false, err
+	}
+
+	// Write to a temporary file so a crash never leaves a truncated chunk behind
+	tmpPath := path + ".part"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return "", false, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", false, err
+	}
+
+	return hash, true, nil
+}
+
+// readChunk loads and decompresses a chunk, verifying its hash
+func (r *Repository) readChunk(hash string) ([]byte, error) {
+	if !validChunkHash(hash) {
+		return nil, fmt.Errorf("invalid chunk hash %q", hash)
+	}
+	file, err := os.Open(r.chunkPath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chunk %s: %w", hash, err)
+	}
+	defer file.Close()
+
+	zr, err := zlib.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk %s: %w", hash, err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk %s: %w", hash, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("chunk %s is corrupted", hash)
+	}
+
+	return data, nil
+}
+
+// chunkSize returns the stored (compressed) size of a chunk
+func (r *Repository) chunkSize(hash string) int64 {
+	info, err := os.Stat(r.chunkPath(hash))
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// LoadSnapshot loads a snapshot index by ID. Snapshots that refer to invalid chunk
+// hashes are rejected as damaged.
+func (r *Repository) LoadSnapshot(id string) (*Snapshot, error) {
+	if err := validateSnapshotID(id); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(r.snapshotPath(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("backup '%s' does not exist in %s", id, r.Path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", id, err)
+	}
+	for _, entry := range snapshot.Files {
+		for _, hash := range entry.Chunks {
+			if !validChunkHash(hash) {
+				return nil, fmt.Errorf("snapshot %s is damaged: invalid chunk hash %q for %s", id, hash, entry.Path)
+			}
+		}
+	}
+	return &snapshot, nil
+}
+
+// saveSnapshot writes a snapshot index to the repository
+func (r *Repository) saveSnapshot(snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	path := r.snapshotPath(snapshot.ID)
+	tmpPath := path + ".part"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Snapshots loads all snapshots in the repository, oldest first.
+// If serverName is not empty, only snapshots of that server are returned.
+func (r *Repository) Snapshots(serverName string) ([]*Snapshot, error) {
+	entries, err := os.ReadDir(filepath.Join(r.Path, snapshotsDirName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots directory: %w", err)
+	}
+
+	var snapshots []*Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), snapshotExt) {
+			continue
+		}
+
+		snapshot, err := r.LoadSnapshot(strings.TrimSuffix(entry.Name(), snapshotExt))
+		if err != nil {
+			return nil, err
+		}
+		if serverName != "" && snapshot.Server != serverName {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
+
+// storeStats counts what a snapshot added to the repository
+type storeStats struct {
+	files      int
+	reused     int
+	bytes      int64
+	newChunks  int
+	newStored  int64
+	seenChunks int
+}
+
+// storeTree splits every file below sourceDir into chunks and returns the resulting snapshot.
+// Files whose size and modification time match the previous snapshot are not read again.
+func (r *Repository) storeTree(snapshot *Snapshot, sourceDir string, previous *Snapshot, skip []string) (*storeStats, error) {
+	// Index the previous snapshot so unchanged files can be reused
+	previousFiles := make(map[string]FileEntry)
+	if previous != nil {
+		for _, entry := range previous.Files {
+			previousFiles[entry.Path] = entry
+		}
+	}
+
+	stats := &storeStats{}
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip excluded paths such as the backup repository itself
+		for _, s := range skip {
+			if path == s {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		name := filepath.ToSlash(relPath)
+
+		// Record directories so empty ones survive a restore
+		if info.IsDir() {
+			snapshot.Dirs = append(snapshot.Dirs, name)
+			return nil
+		}
+
+		// Only regular files are backed up, symlinks and devices are skipped
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		stats.files++
+		stats.bytes += info.Size()
+
+		if prev, exists := previousFiles[name]; exists && prev.Size == info.Size() && prev.ModTime.Equal(info.ModTime()) {
+			snapshot.Files = append(snapshot.Files, prev)
+			stats.reused++
+			stats.seenChunks += len(prev.Chunks)
+			return nil
+		}
+
+		entry, err := r.storeFile(path, name, info, stats)
+		if err != nil {
+			return fmt.Errorf("failed to back up %s: %w", name, err)
+		}
+		snapshot.Files = append(snapshot.Files, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// storeFile splits a single file into chunks and stores the ones not yet in the repository
+func (r *Repository) storeFile(path, name string, info os.FileInfo, stats *storeStats) (FileEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer file.Close()
+
+	size := defaultChunkSize
+	if isRegionFile(name) {
+		size = regionChunkSize
+	}
+
+	entry := FileEntry{
+		Path:    name,
+		Mode:    info.Mode().Perm(),
+		ModTime: info.ModTime(),
+	}
+
+	hash := sha256.New()
+	buf := make([]byte, size)
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			hash.Write(buf[:n])
+			entry.Size += int64(n)
+
+			chunkHash, written, putErr := r.putChunk(buf[:n])
+			if putErr != nil {
+				return FileEntry{}, putErr
+			}
+			if written {
+				stats.newChunks++
+				stats.newStored += r.chunkSize(chunkHash)
+			}
+			stats.seenChunks++
+			entry.Chunks = append(entry.Chunks, chunkHash)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return FileEntry{}, err
+		}
+	}
+
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return entry, nil
+}
+
+// restoreTree writes every file of a snapshot into targetDir and verifies its hash
+func (r *Repository) restoreTree(snapshot *Snapshot, targetDir string) error {
+	// Recreate directories first, including empty ones
+	for _, dir := range snapshot.Dirs {
+		dirPath, err := safeJoin(targetDir, dir)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dirPath, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	var mismatches []string
+	for _, entry := range snapshot.Files {
+		destPath, err := safeJoin(targetDir, entry.Path)
+		if err != nil {
+			return err
+		}
+
+		sum, err := r.restoreFile(entry, destPath)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", entry.Path, err)
+		}
+		if sum != entry.SHA256 {
+			mismatches = append(mismatches, entry.Path)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%d file(s) failed hash verification: %s", len(mismatches), strings.Join(mismatches, ", "))
+	}
+
+	return nil
+}
+
+// restoreFile reassembles a single file from its chunks and returns the SHA-256 of the written data
+func (r *Repository) restoreFile(entry FileEntry, destPath string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return "", err
+	}
+
+	mode := entry.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	out, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, chunkHash := range entry.Chunks {
+		data, err := r.readChunk(chunkHash)
+		if err != nil {
+			out.Close()
+			return "", err
+		}
+		hash.Write(data)
+		if _, err := out.Write(data); err != nil {
+			out.Close()
+			return "", err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
+	// Keep the original modification time
+	os.Chtimes(destPath, entry.ModTime, entry.ModTime)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isRegionFile reports whether a file uses the sector-based region format
+func isRegionFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mca", ".mcr", ".mcc":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+package backup
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSnapshotRejectsInvalidIDs(t *testing.T) {
+	repo, err := OpenRepository(filepath.Join(t.TempDir(), "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A snapshot outside the repository must not be reachable through its ID
+	outside := filepath.Join(filepath.Dir(repo.Path), "x"+snapshotExt)
+	if err := os.WriteFile(outside, []byte(`{"id":"x","files":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"", ".", "..", "../../x", "../x", "a/b", `a\b`, filepath.Join("..", "x")} {
+		if _, err := repo.LoadSnapshot(id); err == nil || !strings.Contains(err.Error(), "invalid backup ID") {
+			t.Errorf("LoadSnapshot(%q) = %v, want an invalid backup ID error", id, err)
+		}
+	}
+}
+
+func TestLoadSnapshotRejectsInvalidChunkHashes(t *testing.T) {
+	repo, err := OpenRepository(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hash := range []string{"", "a", "../../../etc/passwd", strings.Repeat("g", 64), strings.Repeat("a", 63)} {
+		snapshot := Snapshot{ID: "damaged", Files: []FileEntry{{Path: "world/level.dat", Chunks: []string{hash}}}}
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(repo.snapshotPath(snapshot.ID), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.LoadSnapshot(snapshot.ID); err == nil {
+			t.Errorf("LoadSnapshot accepted chunk hash %q", hash)
+		}
+		if _, err := repo.readChunk(hash); err == nil {
+			t.Errorf("readChunk accepted chunk hash %q", hash)
+		}
+	}
+}
+
+// writeTree creates the files of a test server directory. Nil contents create a directory.
+func writeTree(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if data == nil {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkTree checks that dir contains the files and directories of a test server directory
+func checkTree(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, want := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if want == nil {
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				t.Errorf("directory %s was not restored: %v", name, err)
+			}
+			continue
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("file %s was not restored: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("file %s was restored with different contents", name)
+		}
+	}
+}
+
+// randomBytes returns n bytes that do not compress or repeat
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// snapshotInto stores a directory as a new snapshot of the repository
+func snapshotInto(t *testing.T, repo *Repository, id, dir string, previous *Snapshot) (*Snapshot, *storeStats) {
+	t.Helper()
+	snapshot := &Snapshot{ID: id, Server: "test", CreatedAt: time.Now()}
+	stats, err := repo.storeTree(snapshot, dir, previous, nil)
+	if err != nil {
+		t.Fatalf("storeTree returned error: %v", err)
+	}
+	if err := repo.saveSnapshot(snapshot); err != nil {
+		t.Fatalf("saveSnapshot returned error: %v", err)
+	}
+	return snapshot, stats
+}
+
+func TestStoreRestoreRoundTrip(t *testing.T) {
+	repo, err := OpenRepository(filepath.Join(t.TempDir(), "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := t.TempDir()
+
+	// The region file spans several region-sized chunks
+	region := randomBytes(1, 3*regionChunkSize+1000)
+	files := map[string][]byte{
+		"server.properties":        []byte("motd=A Minecraft Server\n"),
+		"world/region/r.0.0.mca":   region,
+		"world/level.dat":          randomBytes(2, 5000),
+		"world/playerdata":         nil,
+		"plugins/empty-config.yml": {},
+		"logs/latest.log":          []byte("[00:00:00] [Server thread/INFO]: Done\n"),
+	}
+	writeTree(t, source, files)
+
+	first, stats := snapshotInto(t, repo, "test_1", source, nil)
+	if stats.files != 5 || stats.reused != 0 {
+		t.Errorf("first snapshot stored %d files, %d reused, want 5 files, none reused", stats.files, stats.reused)
+	}
+	regionEntry := -1
+	for i, entry := range first.Files {
+		if entry.Path == "world/region/r.0.0.mca" {
+			regionEntry = i
+		}
+	}
+	if regionEntry < 0 || len(first.Files[regionEntry].Chunks) != 4 {
+		t.Fatalf("region file was not split into 4 chunks: %+v", first.Files)
+	}
+
+	// Restoring through the repository verifies every file
+	restored := t.TempDir()
+	if err := RestoreBackup(repo.Path, first.ID, restored); err != nil {
+		t.Fatalf("RestoreBackup returned error: %v", err)
+	}
+	checkTree(t, restored, files)
+
+	// The second snapshot reuses unchanged files and stores only new data. A copy of the
+	// region file under another name is deduplicated chunk by chunk.
+	changed := map[string][]byte{
+		"server.properties":             []byte("motd=Changed\n"),
+		"world_nether/region/r.0.0.mca": region,
+	}
+	writeTree(t, source, changed)
+	for name, data := range changed {
+		files[name] = data
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(source, "server.properties"), future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	second, stats := snapshotInto(t, repo, "test_2", source, first)
+	if stats.files != 6 || stats.reused != 4 {
+		t.Errorf("second snapshot stored %d files, %d reused, want 6 files, 4 reused", stats.files, stats.reused)
+	}
+	if stats.newChunks != 1 {
+		t.Errorf("second snapshot wrote %d new chunks, want 1 for server.properties", stats.newChunks)
+	}
+
+	restored = t.TempDir()
+	if err := repo.restoreTree(second, restored); err != nil {
+		t.Fatalf("restoreTree returned error: %v", err)
+	}
+	checkTree(t, restored, files)
+
+	// Both snapshots are listed, and the shared chunks are only stored once
+	backups, err := ListBackups(repo.Path, "test")
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("ListBackups = %d backups, %v, want 2", len(backups), err)
+	}
+	if backups[1].UniqueSize >= backups[1].StoredSize {
+		t.Errorf("second snapshot shares no chunks: unique %d, stored %d", backups[1].UniqueSize, backups[1].StoredSize)
+	}
+}
+
+func TestRestoreDetectsCorruption(t *testing.T) {
+	repo, err := OpenRepository(filepath.Join(t.TempDir(), "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := t.TempDir()
+	writeTree(t, source, map[string][]byte{
+		"server.properties": []byte("motd=A Minecraft Server\n"),
+		"world/level.dat":   randomBytes(3, 5000),
+	})
+	snapshot, _ := snapshotInto(t, repo, "test_1", source, nil)
+
+	var levelDat FileEntry
+	for _, entry := range snapshot.Files {
+		if entry.Path == "world/level.dat" {
+			levelDat = entry
+		}
+	}
+
+	// A chunk whose contents no longer match its hash is reported, not restored
+	chunk := repo.chunkPath(levelDat.Chunks[0])
+	original, err := os.ReadFile(chunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tampered bytes.Buffer
+	zw := zlib.NewWriter(&tampered)
+	zw.Write(randomBytes(4, 5000))
+	zw.Close()
+	if err := os.WriteFile(chunk, tampered.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = RestoreBackup(repo.Path, snapshot.ID, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "corrupted") || !strings.Contains(err.Error(), "world/level.dat") {
+		t.Errorf("RestoreBackup of a tampered chunk = %v, want a corrupted chunk error for world/level.dat", err)
+	}
+
+	// A chunk that cannot be decompressed is reported as well
+	if err := os.WriteFile(chunk, []byte("not zlib"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.restoreTree(snapshot, t.TempDir()); err == nil {
+		t.Error("restoreTree accepted a chunk that is not compressed")
+	}
+
+	// A file whose chunks do not add up to its recorded hash fails verification
+	if err := os.WriteFile(chunk, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := range snapshot.Files {
+		if snapshot.Files[i].Path == "server.properties" {
+			snapshot.Files[i].SHA256 = strings.Repeat("0", 64)
+		}
+	}
+	err = repo.restoreTree(snapshot, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "failed hash verification: server.properties") {
+		t.Errorf("restoreTree with a wrong file hash = %v, want a hash verification error", err)
+	}
+}
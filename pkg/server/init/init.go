@@ -4,38 +4,58 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/0v3rr1de0/mcsrvr/pkg/config"
 	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server/rcon"
 )
 
-// CreateStartupScript creates a startup script for the server
-func CreateStartupScript(serverPath, jarPath, serverName, memory, javaArgs string) (string, error) {
+// jarArgPattern finds the server jar passed to java in a startup script
+var jarArgPattern = regexp.MustCompile(`-jar\s+"?[^"\s]+\.jar"?`)
+
+// CreateStartupScript creates a startup script for the server. Servers installed by the Forge or
+// NeoForge installer are started with its argument files instead of a jar, and proxies are
+// started without nogui.
+func CreateStartupScript(serverPath string, launch *downloader.Launch, serverName, memory, javaPath, javaArgs string, proxy bool) (string, error) {
 	var scriptPath string
 	var scriptContent string
 
+	nogui := " nogui"
+	if proxy {
+		nogui = ""
+	}
+
+	// Use the java on the PATH unless a Java executable was given
+	javaCommand := "java"
+	if javaPath != "" {
+		javaCommand = fmt.Sprintf(`"%s"`, javaPath)
+	}
+
+	target := launchTarget(launch)
+
 	// Determine the script extension based on the OS
 	if isWindows() {
 		scriptPath = filepath.Join(serverPath, "start.bat")
 		scriptContent = fmt.Sprintf(`@echo off
 echo Starting Minecraft server %s...
-java -Xmx%s -Xms%s %s -jar "%s" nogui
+%s -Xmx%s -Xms%s %s %s%s
 if errorlevel 1 (
     echo Server crashed or failed to start. Press any key to exit.
     pause > nul
 )
-`, serverName, memory, memory, javaArgs, filepath.Base(jarPath))
+`, serverName, javaCommand, memory, memory, javaArgs, target, nogui)
 	} else {
 		scriptPath = filepath.Join(serverPath, "start.sh")
 		scriptContent = fmt.Sprintf(`#!/bin/bash
 echo "Starting Minecraft server %s..."
-java -Xmx%s -Xms%s %s -jar "%s" nogui
+%s -Xmx%s -Xms%s %s %s%s
 if [ $? -ne 0 ]; then
     echo "Server crashed or failed to start. Press Enter to exit."
     read
 fi
-`, serverName, memory, memory, javaArgs, filepath.Base(jarPath))
+`, serverName, javaCommand, memory, memory, javaArgs, target, nogui)
 	}
 
 	// Write the script to file
@@ -43,140 +63,155 @@ fi
 		return "", fmt.Errorf("failed to create startup script: %w", err)
 	}
 
-	// Create or update server.properties to enable RCON
-	if err := rcon.EnableRCON(serverPath); err != nil {
-		return "", fmt.Errorf("failed to enable RCON: %w", err)
-	}
-
 	return scriptPath, nil
 }
 
-// AcceptEULA accepts the Minecraft EULA by creating or modifying the eula.txt file
-func AcceptEULA(serverPath string) error {
-	eulaPath := filepath.Join(serverPath, "eula.txt")
-	eulaContent := "eula=true\n"
-
-	if err := os.WriteFile(eulaPath, []byte(eulaContent), 0644); err != nil {
-		return fmt.Errorf("failed to accept EULA: %w", err)
+// launchTarget returns the java arguments that run the server: the server jar, or the
+// argument files written by an installer
+func launchTarget(launch *downloader.Launch) string {
+	if len(launch.ArgsFiles) > 0 {
+		var argsFiles []string
+		for _, argsFile := range launch.ArgsFiles {
+			argsFiles = append(argsFiles, "@"+argsFile)
+		}
+		return strings.Join(argsFiles, " ")
 	}
-
-	return nil
+	return fmt.Sprintf(`-jar "%s"`, filepath.Base(launch.Jar))
 }
 
-// InitializeServer initializes a new Minecraft server
-func InitializeServer(serverPath, serverName, serverType, version, memory, javaArgs string) error {
-	// Create the server directory if it doesn't exist
-	if err := os.MkdirAll(serverPath, 0755); err != nil {
-		return fmt.Errorf("failed to create server directory: %w", err)
+// UpdateStartupScript replaces how the server is launched in its startup scripts, for example
+// after an upgrade, and keeps any other changes made to the scripts
+func UpdateStartupScript(serverPath string, oldLaunch, newLaunch *downloader.Launch) error {
+	oldTarget := launchTarget(oldLaunch)
+	newTarget := launchTarget(newLaunch)
+	if oldTarget == newTarget {
+		return nil
 	}
 
-	// Download the server jar based on the server type
-	var jarPath string
-	var err error
-
-	switch serverType {
-	case "papermc":
-		// Download PaperMC server jar
-		jarPath, err = downloadPaperMC(serverPath, version)
-	case "vanilla":
-		// Download vanilla server jar
-		jarPath, err = downloadVanilla(serverPath, version)
-	case "fabric":
-		// Download Fabric server jar
-		jarPath, err = downloadFabric(serverPath, version, "")
-	default:
-		return fmt.Errorf("unsupported server type: %s", serverType)
+	for _, script := range []string{"start.sh", "start.bat"} {
+		scriptPath := filepath.Join(serverPath, script)
+		content, err := os.ReadFile(scriptPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read startup script: %w", err)
+		}
+
+		// Scripts edited by hand may refer to the jar differently
+		updated := strings.ReplaceAll(string(content), oldTarget, newTarget)
+		if updated == string(content) {
+			updated = jarArgPattern.ReplaceAllLiteralString(string(content), newTarget)
+		}
+		if updated == string(content) {
+			return fmt.Errorf("%s does not contain %s, update it manually", script, oldTarget)
+		}
+
+		if err := os.WriteFile(scriptPath, []byte(updated), 0755); err != nil {
+			return fmt.Errorf("failed to update startup script: %w", err)
+		}
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to download server jar: %w", err)
-	}
+	return nil
+}
 
-	// Create the startup script
-	_, err = CreateStartupScript(serverPath, jarPath, serverName, memory, javaArgs)
+// SetupRCON chooses a free RCON port and a random password for a new server
+// and enables RCON in its server.properties
+func SetupRCON(serverPath string) (config.RCONConfig, error) {
+	rconConfig, err := rcon.NewRCONConfig()
 	if err != nil {
-		return err
+		return config.RCONConfig{}, fmt.Errorf("failed to choose RCON settings: %w", err)
 	}
 
-	// Run the server once to generate the eula.txt file
-	fmt.Println("Running server for the first time to generate eula.txt...")
+	// Create or update server.properties to enable RCON
+	if err := rcon.EnableRCON(serverPath, rconConfig.Port, rconConfig.Password); err != nil {
+		return config.RCONConfig{}, fmt.Errorf("failed to enable RCON: %w", err)
+	}
 
-	// This is a placeholder implementation
-	// In a real implementation, we would need to run the server and wait for it to generate the eula.txt file
+	fmt.Printf("RCON enabled on port %d\n", rconConfig.Port)
+	return rconConfig, nil
+}
 
-	// Accept the EULA
-	if err := AcceptEULA(serverPath); err != nil {
-		return err
-	}
+// AcceptEULA accepts the Minecraft EULA by creating or modifying the eula.txt file
+func AcceptEULA(serverPath string) error {
+	eulaPath := filepath.Join(serverPath, "eula.txt")
+	eulaContent := "eula=true\n"
 
-	// Add the server to the configuration
-	if err := config.AddServer(serverName, serverType, version, serverPath, memory, javaArgs); err != nil {
-		return fmt.Errorf("failed to add server to configuration: %w", err)
+	if err := os.WriteFile(eulaPath, []byte(eulaContent), 0644); err != nil {
+		return fmt.Errorf("failed to accept EULA: %w", err)
 	}
 
-	fmt.Printf("Server '%s' initialized successfully at %s\n", serverName, serverPath)
-	fmt.Printf("To start the server, run: mcsrvr start %s\n", serverName)
-
 	return nil
 }
 
-// InitializeFabricServer initializes a new Fabric server
-func InitializeFabricServer(serverPath, serverName, mcVersion, loaderVersion, memory, javaArgs string) error {
+// InitializeServer initializes a new Minecraft server. The build selects a specific build of
+// the server software, such as a PaperMC build or a Fabric loader version; if it is empty,
+// the server type's default build is used. The Java executable is used to run installers
+// and the server, and defaults to the java on the PATH.
+func InitializeServer(serverPath, serverName, serverType, version, build, memory, javaPath, javaArgs string) error {
 	// Create the server directory if it doesn't exist
 	if err := os.MkdirAll(serverPath, 0755); err != nil {
 		return fmt.Errorf("failed to create server directory: %w", err)
 	}
 
-	// Download the Fabric server jar
-	jarPath, err := downloadFabric(serverPath, mcVersion, loaderVersion)
+	// Download the server jar through the provider of the server type
+	download, launch, err := downloader.Install(serverType, serverPath, version, build, javaPath)
 	if err != nil {
-		return fmt.Errorf("failed to download Fabric server jar: %w", err)
+		return fmt.Errorf("failed to install server: %w", err)
 	}
 
 	// Create the startup script
-	_, err = CreateStartupScript(serverPath, jarPath, serverName, memory, javaArgs)
+	proxy := downloader.IsProxy(serverType)
+	_, err = CreateStartupScript(serverPath, launch, serverName, memory, javaPath, javaArgs, proxy)
 	if err != nil {
 		return err
 	}
 
-	// Run the server once to generate the eula.txt file
-	fmt.Println("Running server for the first time to generate eula.txt...")
-
-	// This is a placeholder implementation
-	// In a real implementation, we would need to run the server and wait for it to generate the eula.txt file
-
-	// Accept the EULA
-	if err := AcceptEULA(serverPath); err != nil {
-		return err
+	// Proxies have neither RCON nor a EULA, and are managed through their console only
+	var rconConfig config.RCONConfig
+	if !proxy {
+		// Give the server its own RCON port and password
+		rconConfig, err = SetupRCON(serverPath)
+		if err != nil {
+			return err
+		}
+
+		// Run the server once to generate the eula.txt file
+		fmt.Println("Running server for the first time to generate eula.txt...")
+
+		// This is a placeholder implementation
+		// In a real implementation, we would need to run the server and wait for it to generate the eula.txt file
+
+		// Accept the EULA
+		if err := AcceptEULA(serverPath); err != nil {
+			return err
+		}
 	}
 
-	// Add the server to the configuration
-	if err := config.AddServer(serverName, "fabric", mcVersion, serverPath, memory, javaArgs); err != nil {
+	// Add the server to the configuration with the version that was actually installed
+	serverConfig := config.ServerConfig{
+		Name:      serverName,
+		Type:      serverType,
+		Version:   download.Version,
+		Build:     download.Build,
+		Path:      serverPath,
+		Memory:    memory,
+		JavaArgs:  javaArgs,
+		Jar:       launch.Jar,
+		ArgsFiles: launch.ArgsFiles,
+		JavaPath:  javaPath,
+		RCON:      rconConfig,
+	}
+	if err := config.AddServer(serverConfig); err != nil {
 		return fmt.Errorf("failed to add server to configuration: %w", err)
 	}
 
-	fmt.Printf("Fabric server '%s' initialized successfully at %s\n", serverName, serverPath)
+	fmt.Printf("Server '%s' initialized successfully at %s\n", serverName, serverPath)
 	fmt.Printf("To start the server, run: mcsrvr start %s\n", serverName)
-	fmt.Println("Note: Most mods will also require you to install Fabric API into the mods folder")
 
 	return nil
 }
 
-// downloadPaperMC downloads the PaperMC server jar
-func downloadPaperMC(serverPath, version string) (string, error) {
-	return downloader.DownloadPaperMC(serverPath, version)
-}
-
-// downloadVanilla downloads the vanilla Minecraft server jar
-func downloadVanilla(serverPath, version string) (string, error) {
-	return downloader.DownloadVanilla(serverPath, version)
-}
-
-// downloadFabric downloads the Fabric server jar
-func downloadFabric(serverPath, version, loaderVersion string) (string, error) {
-	return downloader.DownloadFabric(serverPath, version, loaderVersion)
-}
-
 // isWindows returns true if the current OS is Windows
 func isWindows() bool {
 	return os.PathSeparator == '\\' && os.PathListSeparator == ';'
@@ -0,0 +1,90 @@
+package logs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LatestLogPath returns the path to the latest.log file written by a Minecraft server
+func LatestLogPath(serverPath string) string {
+	return filepath.Join(serverPath, "logs", "latest.log")
+}
+
+// Offset returns the current size of a log file, or 0 if it does not exist yet.
+// Pass the result to WaitFor to only look at lines written afterwards.
+func Offset(logPath string) int64 {
+	info, err := os.Stat(logPath)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// Follower reads the lines appended to a log file, starting at an offset
+type Follower struct {
+	path    string
+	offset  int64
+	partial string
+}
+
+// NewFollower returns a Follower that reads the lines written to a log file after offset
+func NewFollower(logPath string, offset int64) *Follower {
+	return &Follower{path: logPath, offset: offset}
+}
+
+// ReadLines returns the complete lines written since the previous call, without line endings
+func (f *Follower) ReadLines() []string {
+	// Start over if the log was rotated and is now smaller than our offset
+	if size := Offset(f.path); size < f.offset {
+		f.offset = 0
+		f.partial = ""
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+		return nil
+	}
+
+	var lines []string
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		f.offset += int64(len(line))
+		if err != nil {
+			// Keep incomplete lines until the rest has been written
+			f.partial += line
+			return lines
+		}
+
+		lines = append(lines, strings.TrimRight(f.partial+line, "\r\n"))
+		f.partial = ""
+	}
+}
+
+// WaitFor waits until a line containing match is written to the log file after offset
+func WaitFor(logPath string, offset int64, match string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	follower := NewFollower(logPath, offset)
+
+	for time.Now().Before(deadline) {
+		for _, line := range follower.ReadLines() {
+			if strings.Contains(line, match) {
+				return line, nil
+			}
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	return "", fmt.Errorf("timed out after %s waiting for %q in %s", timeout, match, logPath)
+}
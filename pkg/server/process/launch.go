@@ -0,0 +1,93 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+)
+
+// jarPattern finds the server jar in startup scripts written by mcsrvr
+var jarPattern = regexp.MustCompile(`-jar\s+"?([^"\s]+\.jar)"?`)
+
+// JavaCommand builds the command that runs the server JVM directly, without a shell,
+// so the PID of the started process is the PID of the server itself
+func JavaCommand(serverConfig config.ServerConfig) (*exec.Cmd, error) {
+	javaPath := serverConfig.JavaPath
+	if javaPath == "" {
+		javaPath = "java"
+	}
+	javaPath, err := exec.LookPath(javaPath)
+	if err != nil {
+		return nil, fmt.Errorf("java executable not found, install Java or set the server's javaPath: %w", err)
+	}
+
+	args := []string{}
+	if serverConfig.Memory != "" {
+		args = append(args, "-Xmx"+serverConfig.Memory, "-Xms"+serverConfig.Memory)
+	}
+	args = append(args, strings.Fields(serverConfig.JavaArgs)...)
+
+	if len(serverConfig.ArgsFiles) > 0 {
+		// Forge and NeoForge servers are launched with the argument files of their installer
+		for _, argsFile := range serverConfig.ArgsFiles {
+			if _, err := os.Stat(filepath.Join(serverConfig.Path, argsFile)); os.IsNotExist(err) {
+				return nil, fmt.Errorf("argument file does not exist: %s", filepath.Join(serverConfig.Path, argsFile))
+			}
+			args = append(args, "@"+argsFile)
+		}
+	} else {
+		jar := serverConfig.Jar
+		if jar == "" {
+			// Servers created before the jar was stored in the configuration
+			jar, err = FindServerJar(serverConfig.Path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, err := os.Stat(filepath.Join(serverConfig.Path, jar)); os.IsNotExist(err) {
+			return nil, fmt.Errorf("server jar does not exist: %s", filepath.Join(serverConfig.Path, jar))
+		}
+		args = append(args, "-jar", jar)
+	}
+
+	// Proxies do not understand nogui, since they never have a GUI
+	if !downloader.IsProxy(serverConfig.Type) {
+		args = append(args, "nogui")
+	}
+
+	cmd := exec.Command(javaPath, args...)
+	cmd.Dir = serverConfig.Path
+	return cmd, nil
+}
+
+// FindServerJar determines the server jar of a server from its startup script,
+// or from the only jar in the server directory
+func FindServerJar(serverPath string) (string, error) {
+	// Look for the jar referenced by the startup scripts
+	for _, script := range []string{"start.sh", "start.bat"} {
+		content, err := os.ReadFile(filepath.Join(serverPath, script))
+		if err != nil {
+			continue
+		}
+		if match := jarPattern.FindStringSubmatch(string(content)); match != nil {
+			return match[1], nil
+		}
+	}
+
+	// Fall back to the only jar in the server directory
+	jars, err := filepath.Glob(filepath.Join(serverPath, "*.jar"))
+	if err != nil {
+		return "", fmt.Errorf("failed to search for server jar: %w", err)
+	}
+	if len(jars) == 1 {
+		return filepath.Base(jars[0]), nil
+	}
+
+	return "", fmt.Errorf("could not determine the server jar in %s, set the server's jar in the configuration", serverPath)
+}
@@ -1,16 +1,17 @@
 package process
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/store"
 )
 
 // ServerProcess represents a running Minecraft server process
@@ -18,41 +19,53 @@ type ServerProcess struct {
 	Name    string
 	PID     int
 	Running bool
+	// StartTime is when the process was started, used to tell it apart from a
+	// later process that reuses the same PID
+	StartTime time.Time
+	// SupervisorPID is the PID of the mcsrvr process that watches the server, if any
+	SupervisorPID       int
+	SupervisorStartTime time.Time
+	// StopRequested tells the supervisor that the server is being stopped on purpose
+	StopRequested bool
 }
 
 // ServerProcessInfo represents the serializable information about a running server process
 type ServerProcessInfo struct {
-	Name    string `json:"name"`
-	PID     int    `json:"pid"`
-	Running bool   `json:"running"`
-	Path    string `json:"path"`
+	Name      string    `json:"name"`
+	PID       int       `json:"pid"`
+	Running   bool      `json:"running"`
+	Path      string    `json:"path"`
+	StartTime time.Time `json:"startTime,omitempty"`
+	// SupervisorPID is the PID of the mcsrvr process that watches the server, if any
+	SupervisorPID       int       `json:"supervisorPid,omitempty"`
+	SupervisorStartTime time.Time `json:"supervisorStartTime,omitempty"`
+	StopRequested       bool      `json:"stopRequested,omitempty"`
 }
 
-// ActiveServers keeps track of running server processes
+// startTimeTolerance is how far a process start time may differ from the recorded one
+// and still be considered the same process
+const startTimeTolerance = 2 * time.Second
+
+// ActiveServers keeps track of running server processes. It is only changed by
+// ReloadActiveServers and UpdateActiveServers, and may only be used directly inside the
+// function passed to UpdateActiveServers. Everywhere else, use GetActiveServer.
 var ActiveServers = make(map[string]*ServerProcess)
 
+// activeServersMu guards ActiveServers, which goroutines of the scheduler daemon share
+var activeServersMu sync.Mutex
+
 // getActiveServersFilePath returns the path to the file where active servers are stored
 func getActiveServersFilePath() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	stateDir := config.StateDir()
+	if stateDir == "" {
+		return "", fmt.Errorf("the mcsrvr directories are not initialized")
 	}
 
-	configDir := filepath.Join(homeDir, ".mcsrvr")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	return filepath.Join(configDir, "active_servers.json"), nil
+	return filepath.Join(stateDir, "active_servers.json"), nil
 }
 
-// SaveActiveServers saves the active servers to a file
-func SaveActiveServers() error {
-	filePath, err := getActiveServersFilePath()
-	if err != nil {
-		return err
-	}
-
+// saveActiveServers saves the active servers to a file. The caller must hold the lock of the file.
+func saveActiveServers(filePath string) error {
 	// Convert ActiveServers to a serializable format
 	activeServersInfo := make(map[string]ServerProcessInfo)
 	for name, process := range ActiveServers {
@@ -64,62 +77,92 @@ func SaveActiveServers() error {
 		}
 
 		activeServersInfo[name] = ServerProcessInfo{
-			Name:    process.Name,
-			PID:     process.PID,
-			Running: process.Running,
-			Path:    path,
+			Name:      process.Name,
+			PID:       process.PID,
+			Running:   process.Running,
+			Path:      path,
+			StartTime: process.StartTime,
+
+			SupervisorPID:       process.SupervisorPID,
+			SupervisorStartTime: process.SupervisorStartTime,
+			StopRequested:       process.StopRequested,
 		}
 	}
 
-	// Marshal to JSON
-	data, err := json.MarshalIndent(activeServersInfo, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal active servers: %w", err)
-	}
-
-	// Write to file
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write active servers file: %w", err)
+	// Write the file atomically, so supervisors never read a partial file
+	if err := store.SaveJSON(filePath, activeServersInfo); err != nil {
+		return fmt.Errorf("failed to save active servers: %w", err)
 	}
 
 	return nil
 }
 
-// LoadActiveServers loads the active servers from a file
-func LoadActiveServers() error {
+// UpdateActiveServers reloads the active servers, lets fn change ActiveServers and saves
+// them, all while holding the lock of the active servers file. Every change to the active
+// servers goes through it, so concurrent mcsrvr processes do not overwrite each other's
+// entries. Nothing is saved if fn returns an error.
+func UpdateActiveServers(fn func() error) error {
 	filePath, err := getActiveServersFilePath()
 	if err != nil {
 		return err
 	}
 
-	// Check if the file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		// No active servers file, that's okay
-		return nil
+	lock, err := store.Lock(filePath)
+	if err != nil {
+		return err
 	}
+	defer lock.Unlock()
 
-	// Read the file
-	data, err := os.ReadFile(filePath)
+	activeServersMu.Lock()
+	defer activeServersMu.Unlock()
+
+	if err := reloadActiveServers(); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	return saveActiveServers(filePath)
+}
+
+// loadActiveServers loads the active servers from a file. If the file is damaged, the
+// last good version is loaded from its .bak file. The caller must hold activeServersMu.
+func loadActiveServers() error {
+	filePath, err := getActiveServersFilePath()
 	if err != nil {
-		return fmt.Errorf("failed to read active servers file: %w", err)
+		return err
 	}
 
-	// Unmarshal from JSON
+	// Read the file
 	var activeServersInfo map[string]ServerProcessInfo
-	if err := json.Unmarshal(data, &activeServersInfo); err != nil {
-		return fmt.Errorf("failed to unmarshal active servers: %w", err)
+	if err := store.LoadJSON(filePath, &activeServersInfo); err != nil {
+		if os.IsNotExist(err) {
+			// No active servers file, that's okay
+			return nil
+		}
+		return fmt.Errorf("failed to load active servers: %w", err)
 	}
 
 	// Convert to ActiveServers format
 	for name, info := range activeServersInfo {
-		// Check if the process is still running
-		running := IsProcessRunning(info.PID)
+		// Check if the process is still running and was not replaced by another one
+		running := IsServerProcess(info.PID, info.StartTime)
 
-		if running {
+		// A server whose supervisor is still alive is kept while it waits to be restarted
+		supervised := info.SupervisorPID != 0 && IsServerProcess(info.SupervisorPID, info.SupervisorStartTime)
+
+		if running || supervised {
 			ActiveServers[name] = &ServerProcess{
-				Name:    info.Name,
-				PID:     info.PID,
-				Running: true,
+				Name:      info.Name,
+				PID:       info.PID,
+				Running:   running,
+				StartTime: info.StartTime,
+
+				SupervisorPID:       info.SupervisorPID,
+				SupervisorStartTime: info.SupervisorStartTime,
+				StopRequested:       info.StopRequested,
 			}
 		}
 	}
@@ -127,6 +170,43 @@ func LoadActiveServers() error {
 	return nil
 }
 
+// Supervised checks if the server is watched by a supervisor that is still running
+func (p *ServerProcess) Supervised() bool {
+	return p.SupervisorPID != 0 && IsServerProcess(p.SupervisorPID, p.SupervisorStartTime)
+}
+
+// ReloadActiveServers discards the in-memory state and loads the active servers from file again.
+// Long-running commands use it to pick up servers started or stopped by other mcsrvr invocations.
+func ReloadActiveServers() error {
+	activeServersMu.Lock()
+	defer activeServersMu.Unlock()
+
+	return reloadActiveServers()
+}
+
+// reloadActiveServers is ReloadActiveServers for callers that hold activeServersMu
+func reloadActiveServers() error {
+	for name := range ActiveServers {
+		delete(ActiveServers, name)
+	}
+
+	return loadActiveServers()
+}
+
+// GetActiveServer returns a copy of the active server entry of a server, and whether the
+// server is active
+func GetActiveServer(serverName string) (*ServerProcess, bool) {
+	activeServersMu.Lock()
+	defer activeServersMu.Unlock()
+
+	proc, exists := ActiveServers[serverName]
+	if !exists {
+		return nil, false
+	}
+	copied := *proc
+	return &copied, true
+}
+
 // IsProcessRunning checks if a process with the given PID is running
 func IsProcessRunning(pid int) bool {
 	if pid <= 0 {
@@ -152,101 +232,41 @@ func IsProcessRunning(pid int) bool {
 	return false
 }
 
-// FindJavaPID finds the PID of the Java process for a Minecraft server
-func FindJavaPID() (int, error) {
-	if runtime.GOOS == "windows" {
-		// On Windows, use tasklist to find java.exe processes
-		cmd := exec.Command("tasklist", "/FI", "IMAGENAME eq java.exe", "/NH", "/FO", "CSV")
-		output, err := cmd.Output()
-		if err != nil {
-			return 0, fmt.Errorf("failed to execute tasklist: %w", err)
-		}
-
-		// Parse the output to find the Java PID
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-
-			// Parse the CSV line
-			parts := strings.Split(line, ",")
-			if len(parts) < 2 {
-				continue
-			}
-
-			// Extract the PID
-			pidStr := strings.Trim(parts[1], "\"")
-			pid, err := strconv.Atoi(pidStr)
-			if err != nil {
-				continue
-			}
-
-			// Return the first Java PID we find
-			// In a real implementation, we would need to be more specific
-			// to find the correct Java process for the server
-			return pid, nil
-		}
-
-		return 0, fmt.Errorf("no Java process found")
-	} else {
-		// On Unix-like systems, use ps to find java processes
-		cmd := exec.Command("ps", "-ef", "|", "grep", "java", "|", "grep", "-v", "grep")
-		output, err := cmd.Output()
-		if err != nil {
-			return 0, fmt.Errorf("failed to execute ps: %w", err)
-		}
-
-		// Parse the output to find the Java PID
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-
-			// Parse the line
-			parts := strings.Fields(line)
-			if len(parts) < 2 {
-				continue
-			}
-
-			// Extract the PID
-			pid, err := strconv.Atoi(parts[1])
-			if err != nil {
-				continue
-			}
-
-			// Return the first Java PID we find
-			// In a real implementation, we would need to be more specific
-			// to find the correct Java process for the server
-			return pid, nil
-		}
-
-		return 0, fmt.Errorf("no Java process found")
+// IsServerProcess checks if the process with the given PID is running and is the process
+// that was started at startTime. A zero startTime, recorded by older versions of mcsrvr,
+// only checks that the PID is running.
+func IsServerProcess(pid int, startTime time.Time) bool {
+	if !IsProcessRunning(pid) {
+		return false
 	}
-}
-
-// RefreshServerStatus updates the status of all active servers
-func RefreshServerStatus() {
-	for name, process := range ActiveServers {
-		process.Running = IsProcessRunning(process.PID)
-		if !process.Running {
-			delete(ActiveServers, name)
-		}
+	if startTime.IsZero() {
+		return true
 	}
 
-	// Save the updated active servers
-	if err := SaveActiveServers(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to save active servers: %v\n", err)
+	actual, err := ProcessStartTime(pid)
+	if err != nil {
+		// The process may have exited in the meantime
+		return false
 	}
+
+	// A different start time means the PID was reused by another process
+	diff := actual.Sub(startTime)
+	return diff > -startTimeTolerance && diff < startTimeTolerance
 }
 
-// init loads the active servers when the package is initialized
-func init() {
-	if err := LoadActiveServers(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to load active servers: %v\n", err)
+// RefreshServerStatus loads the active servers and updates their status, dropping servers
+// that are no longer running. It is run once config.Initialize has resolved the directories.
+func RefreshServerStatus() {
+	err := UpdateActiveServers(func() error {
+		for name, process := range ActiveServers {
+			process.Running = IsServerProcess(process.PID, process.StartTime)
+			if !process.Running && !process.Supervised() {
+				delete(ActiveServers, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to refresh active servers: %v\n", err)
 	}
-
-	// Refresh the status of all active servers
-	RefreshServerStatus()
 }
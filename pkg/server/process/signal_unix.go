@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package process
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Terminate asks a process to exit by sending it SIGTERM, which lets the JVM run its shutdown hooks
+func Terminate(pid int) error {
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to send SIGTERM: %w", err)
+	}
+	return nil
+}
+
+// Kill forcibly ends a process by sending it SIGKILL
+func Kill(pid int) error {
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+		return fmt.Errorf("failed to send SIGKILL: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package process
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Terminate asks a process to exit using taskkill without /F
+func Terminate(pid int) error {
+	if output, err := exec.Command("taskkill", "/PID", fmt.Sprintf("%d", pid)).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to terminate process: %w: %s", err, output)
+	}
+	return nil
+}
+
+// Kill forcibly ends a process using taskkill /F
+func Kill(pid int) error {
+	if output, err := exec.Command("taskkill", "/F", "/PID", fmt.Sprintf("%d", pid)).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to kill process: %w: %s", err, output)
+	}
+	return nil
+}
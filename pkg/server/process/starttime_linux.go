@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the kernel's USER_HZ, which is 100 on all common Linux platforms
+const clockTicks = 100
+
+// ProcessStartTime returns the time a process was started
+func ProcessStartTime(pid int) (time.Time, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read process status: %w", err)
+	}
+
+	// The command name may contain spaces, so parse the fields after its closing parenthesis
+	stat := string(data)
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return time.Time{}, fmt.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(stat[end+1:])
+	// starttime is field 22 of the whole line, i.e. the 20th after the command name
+	if len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse process start time: %w", err)
+	}
+
+	bootTime, err := systemBootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return bootTime.Add(time.Duration(ticks) * time.Second / clockTicks), nil
+}
+
+// systemBootTime returns the time the system was booted
+func systemBootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read system status: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, found := strings.CutPrefix(line, "btime "); found {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("failed to parse boot time: %w", err)
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("boot time not found in /proc/stat")
+}
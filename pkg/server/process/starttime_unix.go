@@ -0,0 +1,28 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package process
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcessStartTime returns the time a process was started
+func ProcessStartTime(pid int) (time.Time, error) {
+	// lstart is printed in the local time zone, e.g. "Mon Mar  3 10:04:05 2025"
+	output, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to execute ps: %w", err)
+	}
+
+	startTime, err := time.ParseInLocation(time.ANSIC, strings.Join(strings.Fields(string(output)), " "), time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse process start time: %w", err)
+	}
+
+	return startTime, nil
+}
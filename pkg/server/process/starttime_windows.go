@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package process
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ProcessStartTime returns the time a process was started
+func ProcessStartTime(pid int) (time.Time, error) {
+	script := fmt.Sprintf("(Get-Process -Id %d).StartTime.ToUniversalTime().ToString('o')", pid)
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query process start time: %w", err)
+	}
+
+	startTime, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(output)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse process start time: %w", err)
+	}
+
+	return startTime, nil
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+	"github.com/0v3rr1de0/mcsrvr/pkg/properties"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
+)
+
+// rconKeys are the server.properties keys that must match the RCON settings in the server
+// configuration, so they are changed through 'mcsrvr config <server> rcon' instead
+var rconKeys = map[string]bool{
+	"enable-rcon":   true,
+	"rcon.port":     true,
+	"rcon.password": true,
+}
+
+// PropertyChange is a value to set in server.properties
+type PropertyChange struct {
+	Key   string
+	Value string
+}
+
+// PropertyDifference is a key whose value differs between the server.properties of two servers
+type PropertyDifference struct {
+	Key string
+	// Value and Other are the values in the two servers, empty if the key is not set
+	Value    string
+	Other    string
+	HasValue bool
+	HasOther bool
+}
+
+// LoadProperties reads the server.properties of a server
+func LoadProperties(serverName string) (*properties.Properties, error) {
+	propertiesPath, err := propertiesPath(serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := properties.Load(propertiesPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("server.properties file does not exist: %s", propertiesPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server.properties: %w", err)
+	}
+	return props, nil
+}
+
+// SetProperties sets values in the server.properties of a server. Values of known vanilla
+// keys are validated unless force is set. If live is set and the server is running, values
+// that the server can change without a restart are also applied through its console.
+func SetProperties(serverName string, changes []PropertyChange, live, force bool) error {
+	// Check every value before anything is written
+	for i, change := range changes {
+		if rconKeys[change.Key] {
+			return fmt.Errorf("%s is managed by mcsrvr, use 'mcsrvr config %s rcon' instead", change.Key, serverName)
+		}
+		if force {
+			continue
+		}
+		value, err := properties.Validate(change.Key, change.Value)
+		if err != nil {
+			return err
+		}
+		changes[i].Value = value
+	}
+
+	propertiesPath, err := propertiesPath(serverName)
+	if err != nil {
+		return err
+	}
+
+	// A server that never ran may not have a server.properties yet
+	props, err := properties.Load(propertiesPath)
+	if os.IsNotExist(err) {
+		props = properties.New()
+	} else if err != nil {
+		return fmt.Errorf("failed to read server.properties: %w", err)
+	}
+
+	// Update the properties
+	var changed []PropertyChange
+	for _, change := range changes {
+		if value, ok := props.Get(change.Key); ok && value == change.Value {
+			continue
+		}
+		if !properties.Known(change.Key) {
+			fmt.Printf("Warning: %s is not a vanilla server.properties key\n", change.Key)
+		}
+		props.Set(change.Key, change.Value)
+		changed = append(changed, change)
+	}
+	if len(changed) == 0 {
+		fmt.Println("server.properties is already up to date")
+		return nil
+	}
+
+	// Write the updated server.properties file
+	if err := props.Save(propertiesPath); err != nil {
+		return fmt.Errorf("failed to write server.properties: %w", err)
+	}
+	for _, change := range changed {
+		fmt.Printf("Set %s=%s\n", change.Key, change.Value)
+	}
+
+	return applyProperties(serverName, changed, live)
+}
+
+// UnsetProperties removes keys from the server.properties of a server. The server uses its
+// default for a removed key, and writes it back to the file the next time it starts.
+func UnsetProperties(serverName string, keys []string) error {
+	for _, key := range keys {
+		if rconKeys[key] {
+			return fmt.Errorf("%s is managed by mcsrvr, use 'mcsrvr config %s rcon' instead", key, serverName)
+		}
+	}
+
+	propertiesPath, err := propertiesPath(serverName)
+	if err != nil {
+		return err
+	}
+	props, err := LoadProperties(serverName)
+	if err != nil {
+		return err
+	}
+
+	// Remove the keys
+	removed := false
+	for _, key := range keys {
+		if props.Unset(key) {
+			fmt.Printf("Unset %s\n", key)
+			removed = true
+		} else {
+			fmt.Printf("%s is not set\n", key)
+		}
+	}
+	if !removed {
+		return nil
+	}
+
+	// Write the updated server.properties file
+	if err := props.Save(propertiesPath); err != nil {
+		return fmt.Errorf("failed to write server.properties: %w", err)
+	}
+
+	if proc, exists := process.GetActiveServer(serverName); exists && proc.Running {
+		fmt.Println("Restart the server for the changes to take effect")
+	}
+	return nil
+}
+
+// DiffProperties compares the server.properties of two servers and returns the keys whose
+// values differ, in alphabetical order
+func DiffProperties(serverName, otherName string) ([]PropertyDifference, error) {
+	props, err := LoadProperties(serverName)
+	if err != nil {
+		return nil, err
+	}
+	other, err := LoadProperties(otherName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the keys of both files
+	keys := props.Keys()
+	for _, key := range other.Keys() {
+		if _, ok := props.Get(key); !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var differences []PropertyDifference
+	for _, key := range keys {
+		value, hasValue := props.Get(key)
+		otherValue, hasOther := other.Get(key)
+		if hasValue == hasOther && value == otherValue {
+			continue
+		}
+		differences = append(differences, PropertyDifference{
+			Key:      key,
+			Value:    value,
+			Other:    otherValue,
+			HasValue: hasValue,
+			HasOther: hasOther,
+		})
+	}
+	return differences, nil
+}
+
+// applyProperties applies changed values to a running server through its console where the
+// server supports it. The other values take effect when the server is restarted.
+func applyProperties(serverName string, changes []PropertyChange, live bool) error {
+	if proc, exists := process.GetActiveServer(serverName); !exists || !proc.Running {
+		return nil
+	}
+	if !live {
+		fmt.Println("Restart the server for the changes to take effect, or use --live to apply what can be changed while it runs")
+		return nil
+	}
+
+	needsRestart := false
+	for _, change := range changes {
+		command := properties.LiveCommand(change.Key, change.Value)
+		if command == "" {
+			needsRestart = true
+			continue
+		}
+		via, err := sendConsoleCommand(serverName, command)
+		if err != nil {
+			return fmt.Errorf("failed to apply %s to the running server: %w", change.Key, err)
+		}
+		fmt.Printf("Applied %s=%s to the running server over %s\n", change.Key, change.Value, via)
+	}
+
+	if needsRestart {
+		fmt.Println("Restart the server for the other changes to take effect")
+	}
+	return nil
+}
+
+// propertiesPath returns the path of the server.properties of a server. Proxies have their
+// own configuration files instead.
+func propertiesPath(serverName string) (string, error) {
+	serverConfig, err := config.GetServer(serverName)
+	if err != nil {
+		return "", err
+	}
+	if downloader.IsProxy(serverConfig.Type) {
+		return "", fmt.Errorf("server '%s' is a proxy, which has no server.properties", serverName)
+	}
+	return filepath.Join(serverConfig.Path, "server.properties"), nil
+}
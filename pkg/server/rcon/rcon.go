@@ -1,109 +1,185 @@
 package rcon
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
 
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+	"github.com/0v3rr1de0/mcsrvr/pkg/properties"
 	"github.com/jltobler/go-rcon"
 )
 
-// RCONPort is the default RCON port for Minecraft servers
-const RCONPort = 25575
+// DefaultRCONHost is the host used to reach the RCON server of a local Minecraft server
+const DefaultRCONHost = "localhost"
 
-// RCONPassword is the default RCON password for Minecraft servers
-const RCONPassword = "mcsrvr"
+// DefaultRCONPort is the first port tried when choosing an RCON port for a new server
+const DefaultRCONPort = 25575
 
-// EnableRCON enables RCON in the server.properties file
-func EnableRCON(serverPath string) error {
-	propertiesPath := filepath.Join(serverPath, "server.properties")
+// LegacyRCONPassword is the password every server used before per-server passwords existed
+const LegacyRCONPassword = "mcsrvr"
 
-	// Check if server.properties exists
-	if _, err := os.Stat(propertiesPath); os.IsNotExist(err) {
-		// Create a new server.properties file with RCON enabled
-		propertiesContent := fmt.Sprintf(`# RCON Configuration
-enable-rcon=true
-rcon.port=%d
-rcon.password=%s
-broadcast-rcon-to-ops=true
-`, RCONPort, RCONPassword)
-
-		if err := os.WriteFile(propertiesPath, []byte(propertiesContent), 0644); err != nil {
-			return fmt.Errorf("failed to create server.properties: %w", err)
-		}
+// maxPortSearch is how many ports above DefaultRCONPort are tried when looking for a free port
+const maxPortSearch = 1000
 
-		return nil
+// GeneratePassword returns a new random RCON password
+func GeneratePassword() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate RCON password: %w", err)
 	}
+	return hex.EncodeToString(buf), nil
+}
 
-	// Read the existing server.properties file
-	content, err := os.ReadFile(propertiesPath)
+// FindFreePort returns the first port from DefaultRCONPort upwards that is not
+// assigned to another configured server and can currently be bound
+func FindFreePort() (int, error) {
+	usedPorts := make(map[int]bool)
+	servers, err := config.ListServers()
 	if err != nil {
-		return fmt.Errorf("failed to read server.properties: %w", err)
+		return 0, err
+	}
+	for _, srv := range servers {
+		if srv.RCON.Port != 0 {
+			usedPorts[srv.RCON.Port] = true
+		}
 	}
 
-	// Convert to string for easier manipulation
-	propertiesContent := string(content)
-
-	// Create a map to store the properties
-	properties := make(map[string]string)
-
-	// Parse the properties file
-	lines := strings.Split(propertiesContent, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
+	for port := DefaultRCONPort; port < DefaultRCONPort+maxPortSearch; port++ {
+		if usedPorts[port] {
 			continue
 		}
 
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
+		// Make sure nothing else on this host is listening on the port
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
 			continue
 		}
+		listener.Close()
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		properties[key] = value
+		return port, nil
 	}
 
-	// Update the RCON properties
-	properties["enable-rcon"] = "true"
-	properties["rcon.port"] = fmt.Sprintf("%d", RCONPort)
-	properties["rcon.password"] = RCONPassword
-	properties["broadcast-rcon-to-ops"] = "true"
-
-	// Convert the properties back to a string
-	var newContent strings.Builder
-
-	// Add any comments at the beginning of the file
-	for _, line := range lines {
-		if strings.HasPrefix(line, "#") {
-			newContent.WriteString(line)
-			newContent.WriteString("\n")
-		}
+	return 0, fmt.Errorf("no free RCON port found between %d and %d", DefaultRCONPort, DefaultRCONPort+maxPortSearch-1)
+}
+
+// NewRCONConfig chooses a free port and a random password for a new server
+func NewRCONConfig() (config.RCONConfig, error) {
+	port, err := FindFreePort()
+	if err != nil {
+		return config.RCONConfig{}, err
+	}
+
+	password, err := GeneratePassword()
+	if err != nil {
+		return config.RCONConfig{}, err
 	}
 
-	// Add the properties
-	for key, value := range properties {
-		newContent.WriteString(key)
-		newContent.WriteString("=")
-		newContent.WriteString(value)
-		newContent.WriteString("\n")
+	return config.RCONConfig{
+		Host:     DefaultRCONHost,
+		Port:     port,
+		Password: password,
+	}, nil
+}
+
+// EnableRCON enables RCON in the server.properties file with the given port and password.
+// Only the RCON settings are changed; the rest of the file is kept as it is.
+func EnableRCON(serverPath string, port int, password string) error {
+	propertiesPath := filepath.Join(serverPath, "server.properties")
+
+	// Read the existing server.properties file, or start a new one
+	props, err := properties.Load(propertiesPath)
+	if os.IsNotExist(err) {
+		props = properties.New()
+		props.Comment("RCON Configuration")
+	} else if err != nil {
+		return fmt.Errorf("failed to read server.properties: %w", err)
 	}
 
+	// Update the RCON properties
+	props.SetBool("enable-rcon", true)
+	props.SetInt("rcon.port", port)
+	props.Set("rcon.password", password)
+	props.SetBool("broadcast-rcon-to-ops", true)
+
 	// Write the updated content back to the file
-	if err := os.WriteFile(propertiesPath, []byte(newContent.String()), 0644); err != nil {
+	if err := props.Save(propertiesPath); err != nil {
 		return fmt.Errorf("failed to update server.properties: %w", err)
 	}
 
 	return nil
 }
 
-// ConnectRCON connects to the RCON server using the go‑rcon package.
+// GetRCONConfig returns the RCON settings of a server. Servers created before per-server
+// settings existed fall back to the values in their server.properties, then to the old defaults.
+func GetRCONConfig(serverName string) (config.RCONConfig, error) {
+	serverConfig, err := config.GetServer(serverName)
+	if err != nil {
+		return config.RCONConfig{}, err
+	}
+
+	// Proxies have no RCON, and falling back to the old defaults could reach another server
+	if downloader.IsProxy(serverConfig.Type) {
+		return config.RCONConfig{}, fmt.Errorf("server '%s' is a proxy, which has no RCON", serverName)
+	}
+
+	rconConfig := serverConfig.RCON
+	if rconConfig.Host == "" {
+		rconConfig.Host = DefaultRCONHost
+	}
+	if rconConfig.Port != 0 && rconConfig.Password != "" {
+		return rconConfig, nil
+	}
+
+	// Fall back to what the server itself was configured with
+	port, password := readRCONProperties(serverConfig.Path)
+	if rconConfig.Port == 0 {
+		rconConfig.Port = port
+	}
+	if rconConfig.Password == "" {
+		rconConfig.Password = password
+	}
+
+	return rconConfig, nil
+}
+
+// readRCONProperties reads the RCON port and password from server.properties,
+// using the old defaults for anything that is missing
+func readRCONProperties(serverPath string) (int, string) {
+	port, password := DefaultRCONPort, LegacyRCONPassword
+
+	props, err := properties.Load(filepath.Join(serverPath, "server.properties"))
+	if err != nil {
+		return port, password
+	}
+
+	if p, err := props.Int("rcon.port", port); err == nil {
+		port = p
+	}
+	if value, _ := props.Get("rcon.password"); value != "" {
+		password = value
+	}
+
+	return port, password
+}
+
+// ConnectRCON connects to the RCON server of a Minecraft server using the go‑rcon package.
 func ConnectRCON(serverName string) (*rcon.Client, error) {
+	// Look up the RCON settings of this server.
+	rconConfig, err := GetRCONConfig(serverName)
+	if err != nil {
+		return nil, err
+	}
+
 	// Construct a new RCON client.
 	// The URL scheme for rcon.NewClient is "rcon://host:port"
-	client := rcon.NewClient(fmt.Sprintf("rcon://localhost:%d", RCONPort), RCONPassword)
+	address := net.JoinHostPort(rconConfig.Host, strconv.Itoa(rconConfig.Port))
+	client := rcon.NewClient("rcon://"+address, rconConfig.Password)
 	return client, nil
 }
 
@@ -111,21 +187,33 @@ func ConnectRCON(serverName string) (*rcon.Client, error) {
 func ExecuteCommand(serverName, command string) error {
 	fmt.Printf("Executing command '%s' on server '%s'...\n", command, serverName)
 
+	// Send the command and wait for the response.
+	response, err := SendCommand(serverName, command)
+	if err != nil {
+		return err
+	}
+
+	// Print the response.
+	fmt.Println(response)
+	return nil
+}
+
+// SendCommand sends a command to a Minecraft server using RCON and returns its response
+// without printing anything.
+func SendCommand(serverName, command string) (string, error) {
 	// Connect to the RCON server.
 	client, err := ConnectRCON(serverName)
 	if err != nil {
-		return fmt.Errorf("failed to connect to RCON server: %w", err)
+		return "", fmt.Errorf("failed to connect to RCON server: %w", err)
 	}
 
 	// Send the command using the client's Send method.
 	response, err := client.Send(command)
 	if err != nil {
-		return fmt.Errorf("failed to execute command: %w", err)
+		return "", fmt.Errorf("failed to execute command: %w", err)
 	}
 
-	// Print the response.
-	fmt.Println(response)
-	return nil
+	return response, nil
 }
 
 // StopServerGracefully stops a Minecraft server gracefully using RCON.
@@ -5,17 +5,24 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
+	"strings"
 	"time"
 
 	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server/backup"
 	serverInit "github.com/0v3rr1de0/mcsrvr/pkg/server/init"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/logs"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
 	"github.com/0v3rr1de0/mcsrvr/pkg/server/rcon"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/supervisor"
 )
 
-// StartServer starts a Minecraft server as a detached process
+// supervisorStartTimeout is how long StartServer waits for the supervisor to launch the JVM
+const supervisorStartTimeout = 30 * time.Second
+
+// StartServer starts a Minecraft server under a detached supervisor process, which
+// restarts the server if it crashes
 func StartServer(serverName string) error {
 	// Get the server configuration
 	serverConfig, err := config.GetServer(serverName)
@@ -24,8 +31,11 @@ func StartServer(serverName string) error {
 	}
 
 	// Check if the server is already running
-	if proc, exists := process.ActiveServers[serverName]; exists && proc.Running {
-		return fmt.Errorf("server '%s' is already running", serverName)
+	if proc, exists := process.GetActiveServer(serverName); exists {
+		if proc.Running {
+			return fmt.Errorf("server '%s' is already running", serverName)
+		}
+		return fmt.Errorf("server '%s' is waiting to be restarted after a crash, run 'mcsrvr stop %s' to cancel", serverName, serverName)
 	}
 
 	// Check if the server directory exists
@@ -33,46 +43,30 @@ func StartServer(serverName string) error {
 		return fmt.Errorf("server directory does not exist: %s", serverConfig.Path)
 	}
 
-	// Determine the startup script path
-	var scriptPath string
-	if runtime.GOOS == "windows" {
-		scriptPath = filepath.Join(serverConfig.Path, "start.bat")
-	} else {
-		scriptPath = filepath.Join(serverConfig.Path, "start.sh")
-	}
-
-	// Check if the startup script exists
-	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return fmt.Errorf("startup script does not exist: %s", scriptPath)
+	// Make sure the java command can be built before handing the server to the supervisor
+	if _, err := process.JavaCommand(serverConfig); err != nil {
+		return err
 	}
 
-	// Create log file for the server
+	// Create log file for the supervisor
 	logDir := filepath.Join(serverConfig.Path, "logs")
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return fmt.Errorf("failed to create logs directory: %w", err)
 	}
 
-	logFile, err := os.OpenFile(
-		filepath.Join(logDir, "server.log"),
-		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
-		0644,
-	)
+	supervisorLogPath := filepath.Join(logDir, "supervisor.log")
+	logFile, err := os.OpenFile(supervisorLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create log file: %w", err)
 	}
 	defer logFile.Close()
 
-	// Create the command to start the server
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		// On Windows, use cmd.exe to run the batch file
-		cmd = exec.Command("cmd", "/c", scriptPath)
-	} else {
-		// For Unix-like systems, use bash to run the shell script
-		cmd = exec.Command("bash", scriptPath)
+	// The supervisor is this executable running the hidden supervise command
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to determine mcsrvr executable: %w", err)
 	}
-
-	// Set the working directory and output files
+	cmd := exec.Command(executable, "supervise", serverName)
 	cmd.Dir = serverConfig.Path
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
@@ -80,126 +74,281 @@ func StartServer(serverName string) error {
 	// Set the process attributes using our helper function
 	cmd.SysProcAttr = process.NewSysProcAttr()
 
-	// Start the server process
+	// Start the supervisor process
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start server: %w", err)
+		return fmt.Errorf("failed to start supervisor: %w", err)
 	}
+	supervisorPID := cmd.Process.Pid
+
+	// Reap the supervisor if it exits while we wait for it
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	// Wait for the supervisor to launch the JVM
+	deadline := time.After(supervisorStartTimeout)
+	for {
+		select {
+		case <-exited:
+			return fmt.Errorf("supervisor exited before the server started, see %s", supervisorLogPath)
+		case <-deadline:
+			return fmt.Errorf("timed out waiting for the server to start, see %s", supervisorLogPath)
+		case <-time.After(200 * time.Millisecond):
+		}
 
-	// Store the command window process PID
-	cmdPID := cmd.Process.Pid
-	fmt.Printf("Server '%s' started with command window PID %d\n", serverName, cmdPID)
-
-	// Release the process to allow the CLI to exit without killing the server
-	if err := cmd.Process.Release(); err != nil {
-		fmt.Printf("Warning: Failed to release process: %v\n", err)
+		if err := process.ReloadActiveServers(); err != nil {
+			return fmt.Errorf("failed to load active servers: %w", err)
+		}
+		if proc, exists := process.GetActiveServer(serverName); exists && proc.Running && proc.SupervisorPID == supervisorPID {
+			fmt.Printf("Server '%s' started with Java process PID %d (supervisor PID %d)\n", serverName, proc.PID, supervisorPID)
+			break
+		}
 	}
 
-	// For Unix, we need to find the Java process PID
-	if runtime.GOOS != "windows" {
-		// Wait a moment for the Java process to start
-		time.Sleep(2 * time.Second)
+	fmt.Printf("Server '%s' started successfully\n", serverName)
+	return nil
+}
 
-		// Find the Java process PID
-		javaPID, err := process.FindJavaPID()
-		if err != nil {
-			fmt.Printf("Warning: Failed to find Java process PID: %v\n", err)
-			fmt.Println("Using command window PID instead")
-		} else {
-			fmt.Printf("Found Java process with PID %d\n", javaPID)
-			cmdPID = javaPID
-		}
-	}
+// DefaultStopTimeout is how long a server is given to shut down after the stop command
+const DefaultStopTimeout = 60 * time.Second
 
-	// Store the process in ActiveServers
-	process.ActiveServers[serverName] = &process.ServerProcess{
-		Name:    serverName,
-		PID:     cmdPID,
-		Running: true,
-	}
+// DefaultKillGracePeriod is how long a server is given to exit after SIGTERM before it is killed
+const DefaultKillGracePeriod = 15 * time.Second
 
-	// Save the active servers to file
-	if err := process.SaveActiveServers(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to save active servers: %v\n", err)
-	}
+// stopCountdownMarks are the remaining times at which a stop countdown is announced in-game
+var stopCountdownMarks = []time.Duration{
+	10 * time.Minute, 5 * time.Minute, time.Minute, 30 * time.Second,
+	10 * time.Second, 5 * time.Second, 3 * time.Second, 2 * time.Second, time.Second,
+}
 
-	// Update the server configuration with the last started time
-	serverConfig.LastStarted = time.Now()
-	if err := config.UpdateServer(serverName, serverConfig); err != nil {
-		return fmt.Errorf("failed to update server configuration: %w", err)
-	}
+// shutdownProgress are the log messages a server writes while shutting down
+var shutdownProgress = []string{
+	"Stopping server", "Saving players", "Saving worlds", "Saving chunks",
+	"All chunks are saved", "All dimensions are saved",
+}
 
-	// We've already released the process earlier, so no need to do it again
+// StopOptions controls how a server is stopped
+type StopOptions struct {
+	// Timeout is how long to wait for the server to exit after the stop command
+	Timeout time.Duration
+	// Warn is how long players are warned with an in-game countdown before the stop command, 0 for none
+	Warn time.Duration
+	// Grace is how long to wait after SIGTERM before the server is killed
+	Grace time.Duration
+}
 
-	fmt.Printf("Server '%s' started successfully\n", serverName)
-	return nil
+// DefaultStopOptions returns the options used when a server is stopped without any
+func DefaultStopOptions() StopOptions {
+	return StopOptions{
+		Timeout: DefaultStopTimeout,
+		Grace:   DefaultKillGracePeriod,
+	}
 }
 
-// StopServer stops a Minecraft server
-func StopServer(serverName string) error {
+// StopServer stops a Minecraft server. Players are warned with a countdown, then the server
+// is sent the stop command and given time to save. Only if it does not exit in time is it sent
+// SIGTERM, and only if it ignores that as well is it killed.
+func StopServer(serverName string, options StopOptions) error {
 	// Get the server configuration
-	_, err := config.GetServer(serverName)
+	serverConfig, err := config.GetServer(serverName)
 	if err != nil {
 		return err
 	}
 
-	// Check if the server is running
-	proc, exists := process.ActiveServers[serverName]
-	if !exists || !proc.Running {
-		return fmt.Errorf("server '%s' is not running", serverName)
+	// Check if the server is running and tell the supervisor that the server is exiting
+	// on purpose, so it is not restarted
+	var proc *process.ServerProcess
+	err = process.UpdateActiveServers(func() error {
+		active, exists := process.ActiveServers[serverName]
+		if !exists {
+			return fmt.Errorf("server '%s' is not running", serverName)
+		}
+
+		active.StopRequested = true
+		copied := *active
+		proc = &copied
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// A crashed server waiting to be restarted only needs its restart cancelled
+	if !proc.Running {
+		fmt.Printf("Cancelled the pending restart of server '%s'\n", serverName)
+		return nil
 	}
 
 	fmt.Printf("Stopping server '%s'...\n", serverName)
 
-	// Store the Java process PID
-	javaPID := proc.PID
+	// Warn the players before the server goes down. Proxies have no command to broadcast with.
+	if options.Warn > 0 && downloader.IsProxy(serverConfig.Type) {
+		fmt.Println("Warning: Proxies cannot announce the stop to players, stopping without a countdown")
+	} else if options.Warn > 0 {
+		announceStop(serverName, options.Warn)
+	}
+
+	// Remember where the log ends so only the shutdown progress is shown
+	logPath := logs.LatestLogPath(serverConfig.Path)
+	follower := logs.NewFollower(logPath, logs.Offset(logPath))
 
-	// Try to stop the server gracefully using RCON
-	if err := rcon.StopServerGracefully(serverName); err != nil {
-		fmt.Printf("Warning: Failed to stop server gracefully: %v\n", err)
-		fmt.Println("Falling back to force kill...")
+	// Ask the server to save and shut down
+	exited := false
+	if via, err := sendConsoleCommand(serverName, downloader.StopCommand(serverConfig.Type)); err != nil {
+		fmt.Printf("Warning: Failed to send stop command: %v\n", err)
 	} else {
-		fmt.Println("RCON stop command sent successfully")
+		fmt.Printf("Stop command sent via %s, waiting up to %s for the server to exit...\n", via, options.Timeout)
+		exited = waitForExit(proc, follower, options.Timeout)
+		if !exited {
+			fmt.Printf("Server did not exit within %s\n", options.Timeout)
+		}
+	}
+
+	// Escalate to SIGTERM, which still lets the JVM run its shutdown hooks
+	if !exited {
+		fmt.Printf("Sending SIGTERM to Java process (PID %d)...\n", proc.PID)
+		if err := process.Terminate(proc.PID); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		exited = waitForExit(proc, follower, options.Grace)
+		if !exited {
+			fmt.Printf("Server did not exit within %s after SIGTERM\n", options.Grace)
+		}
+	}
+
+	// As a last resort, kill the process
+	if !exited {
+		fmt.Printf("Sending SIGKILL to Java process (PID %d)...\n", proc.PID)
+		if err := process.Kill(proc.PID); err != nil {
+			return fmt.Errorf("failed to kill Java process (PID %d): %w", proc.PID, err)
+		}
+		fmt.Printf("Java process (PID %d) killed\n", proc.PID)
+	}
 
-		// Wait a moment for the server to start shutting down
-		time.Sleep(2 * time.Second)
+	// Remove the server from the active servers
+	err = process.UpdateActiveServers(func() error {
+		delete(process.ActiveServers, serverName)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to save active servers: %v\n", err)
 	}
 
-	// Check if the Java process is still running
-	javaRunning := process.IsProcessRunning(javaPID)
+	fmt.Printf("Server '%s' stopped successfully\n", serverName)
 
-	// If the Java process is still running, kill it
-	if javaRunning {
-		fmt.Printf("Java process (PID %d) is still running, attempting to kill it...\n", javaPID)
+	return nil
+}
 
-		if runtime.GOOS == "windows" {
-			// On Windows, use taskkill to kill the Java process
-			killCmd := exec.Command("taskkill", "/F", "/PID", fmt.Sprintf("%d", javaPID))
-			if err := killCmd.Run(); err != nil {
-				fmt.Printf("Warning: Failed to kill Java process: %v\n", err)
-			} else {
-				fmt.Printf("Java process (PID %d) killed successfully\n", javaPID)
-			}
-		} else {
-			// On Unix-like systems, use kill to kill the Java process
-			killCmd := exec.Command("kill", "-9", fmt.Sprintf("%d", javaPID))
-			if err := killCmd.Run(); err != nil {
-				fmt.Printf("Warning: Failed to kill Java process: %v\n", err)
-			} else {
-				fmt.Printf("Java process (PID %d) killed successfully\n", javaPID)
+// announceStop broadcasts a countdown in-game and returns when it reaches zero
+func announceStop(serverName string, warn time.Duration) {
+	marks := []time.Duration{warn}
+	for _, mark := range stopCountdownMarks {
+		if mark < warn {
+			marks = append(marks, mark)
+		}
+	}
+
+	fmt.Printf("Warning players, stopping in %s...\n", FormatCountdown(warn))
+	for i, mark := range marks {
+		if _, err := sendConsoleCommand(serverName, "say Server stopping in "+FormatCountdown(mark)); err != nil {
+			fmt.Printf("Warning: Failed to announce stop: %v\n", err)
+		}
+
+		// Sleep until the next announcement, or until the stop after the last one
+		wait := mark
+		if i+1 < len(marks) {
+			wait = mark - marks[i+1]
+		}
+		time.Sleep(wait)
+	}
+}
+
+// waitForExit waits up to timeout for the server process to exit, printing the shutdown
+// progress the server writes to its log. It reports whether the process exited.
+func waitForExit(proc *process.ServerProcess, follower *logs.Follower, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		for _, line := range follower.ReadLines() {
+			for _, progress := range shutdownProgress {
+				if strings.Contains(line, progress) {
+					fmt.Printf("  %s\n", line)
+					break
+				}
 			}
 		}
+
+		if !process.IsServerProcess(proc.PID, proc.StartTime) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(500 * time.Millisecond)
 	}
+}
 
-	// Update the process status
-	proc.Running = false
-	delete(process.ActiveServers, serverName)
+// sendConsoleCommand sends a console command over RCON, falling back to the server's stdin
+// through its supervisor. It returns how the command was delivered.
+func sendConsoleCommand(serverName, command string) (string, error) {
+	_, rconErr := rcon.SendCommand(serverName, command)
+	if rconErr == nil {
+		return "RCON", nil
+	}
 
-	// Save the active servers to file
-	if err := process.SaveActiveServers(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to save active servers: %v\n", err)
+	proc, exists := process.GetActiveServer(serverName)
+	if !exists || !proc.Supervised() || !supervisor.ConsoleAvailable(serverName) {
+		return "", rconErr
+	}
+	if err := supervisor.SendInput(serverName, command); err != nil {
+		return "", fmt.Errorf("%v, and %w", rconErr, err)
 	}
+	return "console", nil
+}
 
-	fmt.Printf("Server '%s' stopped successfully\n", serverName)
+// FormatCountdown formats a countdown duration for an in-game announcement
+func FormatCountdown(d time.Duration) string {
+	unit := func(n int, name string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", name)
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
+
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return unit(int(d/time.Hour), "hour")
+	case d >= time.Minute && d%time.Minute == 0:
+		return unit(int(d/time.Minute), "minute")
+	default:
+		return unit(int(d.Round(time.Second)/time.Second), "second")
+	}
+}
+
+// RestartServer stops a running Minecraft server and starts it again
+func RestartServer(serverName string) error {
+	// Check if the server is running
+	proc, exists := process.GetActiveServer(serverName)
+	if !exists || !proc.Running {
+		return fmt.Errorf("server '%s' is not running", serverName)
+	}
+
+	// Stop the server
+	if err := StopServer(serverName, DefaultStopOptions()); err != nil {
+		return fmt.Errorf("failed to stop server: %w", err)
+	}
+
+	// Verify that the server is fully stopped
+	if proc, exists := process.GetActiveServer(serverName); exists && proc.Running {
+		return fmt.Errorf("server '%s' is still running after stop command", serverName)
+	}
+
+	// Start the server
+	fmt.Printf("Starting server '%s'...\n", serverName)
+	if err := StartServer(serverName); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
 
 	return nil
 }
@@ -207,48 +356,141 @@ func StopServer(serverName string) error {
 // ExecuteCommand executes a command on a Minecraft server using RCON.
 func ExecuteCommand(serverName, command string) error {
 	// Get the server configuration.
-	_, err := config.GetServer(serverName)
+	serverConfig, err := config.GetServer(serverName)
 	if err != nil {
 		return err
 	}
 
 	// Check if the server is running.
-	if _, exists := process.ActiveServers[serverName]; !exists {
+	if proc, exists := process.GetActiveServer(serverName); !exists || !proc.Running {
 		return fmt.Errorf("server '%s' is not running", serverName)
 	}
 
+	// Proxies have no RCON, so the command goes to their console, which shows the response
+	if downloader.IsProxy(serverConfig.Type) {
+		if err := supervisor.SendInput(serverName, command); err != nil {
+			return err
+		}
+		fmt.Printf("Command sent to the console of proxy '%s', see 'mcsrvr console %s' for its output\n", serverName, serverName)
+		return nil
+	}
+
 	return rcon.ExecuteCommand(serverName, command)
 }
 
-// CreateBackup creates a backup of a Minecraft server
+// saveFlushTimeout is how long a live backup waits for the server to finish flushing the world
+const saveFlushTimeout = 2 * time.Minute
+
+// CreateBackup creates a backup of a Minecraft server.
+// If the server is running, world saving is paused while the files are copied.
 func CreateBackup(serverName, backupPath string) error {
 	// Get the server configuration
-	_, err := config.GetServer(serverName)
+	serverConfig, err := config.GetServer(serverName)
 	if err != nil {
 		return err
 	}
 
-	return backup.CreateBackup(serverName, backupPath)
+	// Offline servers, and proxies which have no world, can be copied as they are
+	if proc, exists := process.GetActiveServer(serverName); !exists || !proc.Running || downloader.IsProxy(serverConfig.Type) {
+		return backup.CreateBackup(serverName, serverConfig.Path, backupPath)
+	}
+
+	return createLiveBackup(serverConfig, backupPath)
+}
+
+// createLiveBackup backs up a running server by disabling world saving, flushing all
+// chunks to disk and re-enabling saving once the copy is done, even if it failed. The
+// commands are sent over RCON, or through the console of a supervised server if RCON fails.
+func createLiveBackup(serverConfig config.ServerConfig, backupPath string) (err error) {
+	serverName := serverConfig.Name
+
+	fmt.Printf("Server '%s' is running, pausing world saving for the backup...\n", serverName)
+	if _, err := sendConsoleCommand(serverName, "save-off"); err != nil {
+		return fmt.Errorf("failed to disable world saving: %w", err)
+	}
+
+	// Always turn saving back on, otherwise the server would stop writing the world
+	defer func() {
+		if _, saveErr := sendConsoleCommand(serverName, "save-on"); saveErr != nil {
+			saveErr = fmt.Errorf("failed to re-enable world saving, run 'mcsrvr cmd %s save-on' manually: %w", serverName, saveErr)
+			if err == nil {
+				err = saveErr
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", saveErr)
+			}
+			return
+		}
+		fmt.Println("World saving re-enabled")
+	}()
+
+	// Remember where the log ends so we only match the confirmation of our own flush
+	logPath := logs.LatestLogPath(serverConfig.Path)
+	offset := logs.Offset(logPath)
+
+	fmt.Println("Flushing world to disk...")
+	if _, err := sendConsoleCommand(serverName, "save-all flush"); err != nil {
+		return fmt.Errorf("failed to flush world: %w", err)
+	}
+
+	if _, err := logs.WaitFor(logPath, offset, "Saved the game", saveFlushTimeout); err != nil {
+		return fmt.Errorf("world flush did not complete: %w", err)
+	}
+	fmt.Println("World saved, copying files...")
+
+	return backup.CreateBackup(serverName, serverConfig.Path, backupPath)
 }
 
 // RestoreBackup restores a backup of a Minecraft server
-func RestoreBackup(backupPath, restorePath string) error {
-	return backup.RestoreBackup(backupPath, restorePath)
+func RestoreBackup(backupPath, backupName, restorePath string) error {
+	return backup.RestoreBackup(backupPath, backupName, restorePath)
 }
 
 // ListBackups lists all backups for a server
-func ListBackups(serverName string) ([]string, error) {
-	return backup.ListBackups(serverName)
+func ListBackups(backupPath, serverName string) ([]backup.BackupInfo, error) {
+	return backup.ListBackups(backupPath, serverName)
 }
 
-// InitializeServer initializes a new Minecraft server
-func InitializeServer(serverPath, serverName, serverType, version, memory, javaArgs string) error {
-	return serverInit.InitializeServer(serverPath, serverName, serverType, version, memory, javaArgs)
+// PruneBackups deletes backups that fall outside the retention policy of a server.
+// If no server name is given, every configured server is pruned with its own policy.
+func PruneBackups(backupPath, serverName string, dryRun bool) error {
+	var servers []config.ServerConfig
+	if serverName != "" {
+		serverConfig, err := config.GetServer(serverName)
+		if err != nil {
+			return err
+		}
+		servers = append(servers, serverConfig)
+	} else {
+		var err error
+		servers, err = config.ListServers()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, serverConfig := range servers {
+		maxTotalSize, err := backup.ParseSize(serverConfig.Retention.MaxTotalSize)
+		if err != nil {
+			return fmt.Errorf("invalid retention policy for server '%s': %w", serverConfig.Name, err)
+		}
+
+		policy := backup.RetentionPolicy{
+			KeepLast:     serverConfig.Retention.KeepLast,
+			KeepDaily:    serverConfig.Retention.KeepDaily,
+			KeepWeekly:   serverConfig.Retention.KeepWeekly,
+			MaxTotalSize: maxTotalSize,
+		}
+		if err := backup.Prune(backupPath, serverConfig.Name, policy, dryRun); err != nil {
+			return fmt.Errorf("failed to prune backups of server '%s': %w", serverConfig.Name, err)
+		}
+	}
+
+	return nil
 }
 
-// InitializeFabricServer initializes a new Fabric server
-func InitializeFabricServer(serverPath, serverName, mcVersion, loaderVersion, memory, javaArgs string) error {
-	return serverInit.InitializeFabricServer(serverPath, serverName, mcVersion, loaderVersion, memory, javaArgs)
+// InitializeServer initializes a new Minecraft server
+func InitializeServer(serverPath, serverName, serverType, version, build, memory, javaPath, javaArgs string) error {
+	return serverInit.InitializeServer(serverPath, serverName, serverType, version, build, memory, javaPath, javaArgs)
 }
 
 // AcceptEULA accepts the Minecraft EULA by creating or modifying the eula.txt file
@@ -260,8 +502,3 @@ func AcceptEULA(serverPath string) error {
 func RefreshServerStatus() {
 	process.RefreshServerStatus()
 }
-
-// init loads the active servers when the package is initialized
-func init() {
-	// The initialization is now handled in the process package
-}
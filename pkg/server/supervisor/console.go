@@ -0,0 +1,330 @@
+package supervisor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+)
+
+// maxScrollback is how much recent console output is replayed to a client when it attaches
+const maxScrollback = 256 * 1024
+
+// clientWriteTimeout is how long a write to a slow client may take before it is dropped
+const clientWriteTimeout = 5 * time.Second
+
+// clientQueueLength is how many chunks of output may wait for a client. A client that falls
+// further behind is dropped, so it never holds up the output of the server.
+const clientQueueLength = 256
+
+// Every client starts by sending the mode it wants on a line of its own: attached clients
+// receive the output of the server, while send clients only deliver input.
+const (
+	consoleModeAttach = "attach"
+	consoleModeSend   = "send"
+)
+
+// ErrConsoleClosed is returned by Attach when the supervisor closes the console because
+// the server was stopped
+var ErrConsoleClosed = errors.New("the console was closed because the server stopped")
+
+// ConsoleSocketPath returns the path of the Unix socket on which the supervisor of a server
+// exposes its console
+func ConsoleSocketPath(serverName string) string {
+	return filepath.Join(config.StateDir(), "run", serverName+".sock")
+}
+
+// console connects the standard streams of the server to the clients attached through the
+// console socket. It outlives a single server process, so clients stay attached across restarts.
+type console struct {
+	mu         sync.Mutex
+	socketPath string
+	socketInfo os.FileInfo
+	listener   net.Listener
+	stdin      io.WriteCloser
+	clients    map[net.Conn]*consoleClient
+	scrollback []byte
+}
+
+// consoleClient is an attached client. Its output is queued and written by a goroutine of
+// its own, so a slow client cannot block the server or the other clients.
+type consoleClient struct {
+	conn net.Conn
+	out  chan []byte
+}
+
+// listenConsole creates the console socket of a server and starts accepting clients
+func listenConsole(serverName string) (*console, error) {
+	socketPath := ConsoleSocketPath(serverName)
+
+	// Only the owner may attach, since attached clients can run any console command
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// Remove a socket left behind by a supervisor that did not exit cleanly
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale console socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on console socket: %w", err)
+	}
+
+	// The socket is removed by close, which checks that it was not replaced by a newer supervisor
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	socketInfo, err := os.Stat(socketPath)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to stat console socket: %w", err)
+	}
+
+	c := &console{
+		socketPath: socketPath,
+		socketInfo: socketInfo,
+		listener:   listener,
+		clients:    make(map[net.Conn]*consoleClient),
+	}
+	go c.accept()
+
+	return c, nil
+}
+
+// accept handles clients until the console is closed
+func (c *console) accept() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		go c.handle(conn)
+	}
+}
+
+// handle serves a single client, forwarding the lines it sends to the server's stdin
+// until it disconnects
+func (c *console) handle(conn net.Conn) {
+	defer func() {
+		c.mu.Lock()
+		c.removeClient(conn)
+		c.mu.Unlock()
+		conn.Close()
+	}()
+
+	// Read the mode the client wants
+	scanner := bufio.NewScanner(conn)
+	conn.SetReadDeadline(time.Now().Add(clientWriteTimeout))
+	if !scanner.Scan() {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	switch scanner.Text() {
+	case consoleModeAttach:
+		// Queue the scrollback before any live output reaches the client
+		client := &consoleClient{conn: conn, out: make(chan []byte, clientQueueLength)}
+		c.mu.Lock()
+		client.out <- append([]byte(nil), c.scrollback...)
+		c.clients[conn] = client
+		c.mu.Unlock()
+		go client.writeLoop()
+	case consoleModeSend:
+	default:
+		return
+	}
+
+	for scanner.Scan() {
+		c.mu.Lock()
+		stdin := c.stdin
+		c.mu.Unlock()
+
+		if stdin == nil {
+			c.writeTo(conn, []byte("The server is not running, input was discarded\n"))
+			continue
+		}
+		if _, err := fmt.Fprintln(stdin, scanner.Text()); err != nil {
+			c.writeTo(conn, []byte(fmt.Sprintf("Failed to send input to the server: %v\n", err)))
+		}
+	}
+}
+
+// writeTo writes a message to a single client. Messages to attached clients are queued
+// behind their output, other clients are only written to by their own handler.
+func (c *console) writeTo(conn net.Conn, message []byte) {
+	c.mu.Lock()
+	if client, attached := c.clients[conn]; attached {
+		c.enqueue(client, message)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+	conn.Write(message)
+}
+
+// Write records server output in the scrollback and queues it for every attached client.
+// It never waits for a client, since the server blocks while its output is not read.
+func (c *console) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scrollback = append(c.scrollback, p...)
+	if len(c.scrollback) > maxScrollback {
+		// Keep whole lines only
+		trimmed := c.scrollback[len(c.scrollback)-maxScrollback:]
+		for i, b := range trimmed {
+			if b == '\n' {
+				trimmed = trimmed[i+1:]
+				break
+			}
+		}
+		c.scrollback = append([]byte(nil), trimmed...)
+	}
+
+	// The caller may reuse p, so the clients get a copy
+	if len(c.clients) > 0 {
+		output := append([]byte(nil), p...)
+		for _, client := range c.clients {
+			c.enqueue(client, output)
+		}
+	}
+
+	return len(p), nil
+}
+
+// enqueue queues output for a client, dropping the client if its queue is full. The caller
+// must hold c.mu.
+func (c *console) enqueue(client *consoleClient, output []byte) {
+	select {
+	case client.out <- output:
+	default:
+		// Drop clients that cannot keep up
+		client.conn.Close()
+		c.removeClient(client.conn)
+	}
+}
+
+// removeClient detaches a client and stops its writer. The caller must hold c.mu.
+func (c *console) removeClient(conn net.Conn) {
+	if client, attached := c.clients[conn]; attached {
+		delete(c.clients, conn)
+		close(client.out)
+	}
+}
+
+// writeLoop writes the queued output to the client until the client is removed
+func (client *consoleClient) writeLoop() {
+	for output := range client.out {
+		client.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+		if _, err := client.conn.Write(output); err != nil {
+			// Drop clients that have gone away or are stuck. Closing the connection ends
+			// the handler, which removes the client.
+			client.conn.Close()
+			for range client.out {
+			}
+			return
+		}
+	}
+}
+
+// setStdin sets the stdin of the running server process, or nil while no process is running
+func (c *console) setStdin(stdin io.WriteCloser) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stdin = stdin
+}
+
+// close stops accepting clients, detaches the attached ones and removes the socket
+func (c *console) close() {
+	c.listener.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for conn := range c.clients {
+		conn.Close()
+		c.removeClient(conn)
+	}
+
+	if info, err := os.Stat(c.socketPath); err == nil && os.SameFile(info, c.socketInfo) {
+		os.Remove(c.socketPath)
+	}
+}
+
+// Attach connects to the console of a supervised server. Output of the server, starting with
+// the scrollback, is copied to out and lines read from in are sent to the server's stdin.
+// Attach returns when in is exhausted, a line containing only "exit" is read, detach is
+// closed or the supervisor exits. Detaching leaves the server running.
+func Attach(serverName string, in io.Reader, out io.Writer, detach <-chan struct{}) error {
+	conn, err := net.Dial("unix", ConsoleSocketPath(serverName))
+	if err != nil {
+		return fmt.Errorf("failed to connect to console of server '%s': %w", serverName, err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintln(conn, consoleModeAttach); err != nil {
+		return fmt.Errorf("failed to attach to console of server '%s': %w", serverName, err)
+	}
+
+	// Stream the server output until the supervisor closes the connection
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(out, conn)
+		close(closed)
+	}()
+
+	// Send input line by line, so nothing is sent before the user presses enter
+	inputDone := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if strings.TrimSpace(scanner.Text()) == "exit" {
+				break
+			}
+			if _, err := fmt.Fprintln(conn, scanner.Text()); err != nil {
+				inputDone <- err
+				return
+			}
+		}
+		inputDone <- scanner.Err()
+	}()
+
+	select {
+	case <-closed:
+		return ErrConsoleClosed
+	case err := <-inputDone:
+		return err
+	case <-detach:
+		return nil
+	}
+}
+
+// SendInput writes a line to the stdin of a supervised server without attaching to its console
+func SendInput(serverName, line string) error {
+	conn, err := net.Dial("unix", ConsoleSocketPath(serverName))
+	if err != nil {
+		return fmt.Errorf("failed to connect to console of server '%s': %w", serverName, err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "%s\n%s\n", consoleModeSend, line); err != nil {
+		return fmt.Errorf("failed to send input to server '%s': %w", serverName, err)
+	}
+	return nil
+}
+
+// ConsoleAvailable checks if the supervisor of a server exposes a console socket
+func ConsoleAvailable(serverName string) bool {
+	_, err := os.Stat(ConsoleSocketPath(serverName))
+	return err == nil
+}
@@ -0,0 +1,113 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/store"
+)
+
+// maxCrashEntries is the number of crashes kept in the crash history file
+const maxCrashEntries = 1000
+
+// CrashEntry represents an unexpected exit of a server
+type CrashEntry struct {
+	Server    string    `json:"server"`
+	StartedAt time.Time `json:"startedAt"`
+	ExitedAt  time.Time `json:"exitedAt"`
+	ExitCode  int       `json:"exitCode"`
+	// Status describes how the process ended, e.g. "exit status 1" or "signal: killed"
+	Status string `json:"status"`
+	// CrashReport is the report the server wrote to crash-reports/, if any
+	CrashReport string `json:"crashReport,omitempty"`
+	// Restarted reports whether the supervisor restarted the server after the crash
+	Restarted bool `json:"restarted"`
+}
+
+// getCrashFilePath returns the path to the file where crashes are recorded
+func getCrashFilePath() string {
+	return filepath.Join(config.StateDir(), "crash_history.json")
+}
+
+// LoadCrashes loads the recorded crashes, oldest first.
+// If serverName is not empty, only crashes of that server are returned.
+func LoadCrashes(serverName string) ([]CrashEntry, error) {
+	var crashes []CrashEntry
+	err := store.LoadJSON(getCrashFilePath(), &crashes)
+	if os.IsNotExist(err) {
+		return []CrashEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load crash history: %w", err)
+	}
+
+	if serverName == "" {
+		return crashes, nil
+	}
+
+	filtered := make([]CrashEntry, 0, len(crashes))
+	for _, crash := range crashes {
+		if crash.Server == serverName {
+			filtered = append(filtered, crash)
+		}
+	}
+	return filtered, nil
+}
+
+// appendCrash adds a crash to the crash history file, dropping the oldest entries beyond the limit
+func appendCrash(crash CrashEntry) error {
+	// Hold the lock while the file is read and written, so entries recorded by other
+	// mcsrvr processes at the same time are kept
+	lock, err := store.Lock(getCrashFilePath())
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	crashes, err := LoadCrashes("")
+	if err != nil {
+		return err
+	}
+
+	crashes = append(crashes, crash)
+	if len(crashes) > maxCrashEntries {
+		crashes = crashes[len(crashes)-maxCrashEntries:]
+	}
+
+	if err := store.SaveJSON(getCrashFilePath(), crashes); err != nil {
+		return fmt.Errorf("failed to save crash history: %w", err)
+	}
+
+	return nil
+}
+
+// findCrashReport returns the newest crash report written since the server was started,
+// or an empty string if there is none
+func findCrashReport(serverPath string, since time.Time) string {
+	entries, err := os.ReadDir(filepath.Join(serverPath, "crash-reports"))
+	if err != nil {
+		return ""
+	}
+
+	var newest string
+	var newestTime time.Time
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(since) || !info.ModTime().After(newestTime) {
+			continue
+		}
+		newest = filepath.Join(serverPath, "crash-reports", entry.Name())
+		newestTime = info.ModTime()
+	}
+
+	return newest
+}
@@ -0,0 +1,327 @@
+package supervisor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
+)
+
+// Defaults used for the parts of a restart policy that are not configured
+const (
+	DefaultMaxRestarts   = 5
+	DefaultRestartWindow = 10 * time.Minute
+	DefaultBackoff       = 5 * time.Second
+	DefaultMaxBackoff    = 5 * time.Minute
+)
+
+// Policy is a parsed restart policy
+type Policy struct {
+	Enabled     bool
+	MaxRestarts int
+	Window      time.Duration
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// ParsePolicy parses the restart policy of a server, filling in the defaults
+func ParsePolicy(restart config.RestartPolicy) (Policy, error) {
+	policy := Policy{
+		Enabled:     !restart.Disabled,
+		MaxRestarts: DefaultMaxRestarts,
+		Window:      DefaultRestartWindow,
+		Backoff:     DefaultBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+	}
+
+	if restart.MaxRestarts < 0 {
+		return Policy{}, fmt.Errorf("max restarts cannot be negative")
+	}
+	if restart.MaxRestarts > 0 {
+		policy.MaxRestarts = restart.MaxRestarts
+	}
+
+	durations := []struct {
+		value  string
+		name   string
+		target *time.Duration
+	}{
+		{restart.Window, "restart window", &policy.Window},
+		{restart.Backoff, "backoff", &policy.Backoff},
+		{restart.MaxBackoff, "max backoff", &policy.MaxBackoff},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed <= 0 {
+			return Policy{}, fmt.Errorf("invalid %s: %s", d.name, d.value)
+		}
+		*d.target = parsed
+	}
+
+	if policy.MaxBackoff < policy.Backoff {
+		policy.MaxBackoff = policy.Backoff
+	}
+
+	return policy, nil
+}
+
+// Run starts a server and watches it until it is stopped. If the server exits unexpectedly,
+// the crash is recorded and the server is restarted with exponential backoff, until the
+// restart policy's limit is reached. Run is executed by the hidden 'mcsrvr supervise' command
+// in a detached process, so the supervisor survives the CLI invocation that started it.
+func Run(serverName string) error {
+	supervisorStart, err := process.ProcessStartTime(os.Getpid())
+	if err != nil {
+		logf("Warning: Failed to get supervisor start time: %v", err)
+	}
+
+	// Expose the console so clients can attach to the server's stdin and stdout
+	con, err := listenConsole(serverName)
+	if err != nil {
+		logf("Warning: Console is not available: %v", err)
+	} else {
+		defer con.close()
+	}
+
+	var restarts []time.Time
+	var backoff time.Duration
+	for {
+		// Load the configuration on every start so changes apply after a crash
+		serverConfig, err := config.GetServer(serverName)
+		if err != nil {
+			return err
+		}
+		policy, err := ParsePolicy(serverConfig.Restart)
+		if err != nil {
+			return err
+		}
+		if backoff == 0 {
+			backoff = policy.Backoff
+		}
+
+		// Run the server until it exits
+		startedAt := time.Now()
+		state, err := runServer(serverConfig, supervisorStart, con)
+		if err != nil {
+			unregister(serverName)
+			return err
+		}
+
+		// A stop through mcsrvr, or a clean shutdown from the console, ends supervision
+		if stopRequested(serverName) {
+			logf("Server '%s' was stopped", serverName)
+			unregister(serverName)
+			return nil
+		}
+		if state.Success() {
+			logf("Server '%s' shut down normally", serverName)
+			unregister(serverName)
+			return nil
+		}
+
+		crash := CrashEntry{
+			Server:      serverName,
+			StartedAt:   startedAt,
+			ExitedAt:    time.Now(),
+			ExitCode:    state.ExitCode(),
+			Status:      state.String(),
+			CrashReport: findCrashReport(serverConfig.Path, startedAt),
+		}
+		logf("Server '%s' exited unexpectedly (%s)", serverName, crash.Status)
+		if crash.CrashReport != "" {
+			logf("Crash report: %s", crash.CrashReport)
+		}
+
+		// Only count the restarts within the window
+		recent := restarts[:0]
+		for _, t := range restarts {
+			if time.Since(t) < policy.Window {
+				recent = append(recent, t)
+			}
+		}
+		restarts = recent
+
+		// A server that ran longer than the window starts over with the initial backoff
+		if time.Since(startedAt) > policy.Window {
+			backoff = policy.Backoff
+		}
+
+		if !policy.Enabled || len(restarts) >= policy.MaxRestarts {
+			if !policy.Enabled {
+				logf("Automatic restarts are disabled for server '%s'", serverName)
+			} else {
+				logf("Server '%s' crashed %d times within %s, giving up", serverName, len(restarts)+1, policy.Window)
+			}
+			if err := appendCrash(crash); err != nil {
+				logf("Warning: Failed to record crash: %v", err)
+			}
+			unregister(serverName)
+			return nil
+		}
+
+		crash.Restarted = true
+		if err := appendCrash(crash); err != nil {
+			logf("Warning: Failed to record crash: %v", err)
+		}
+
+		// Wait before restarting, giving up if the server is stopped in the meantime
+		logf("Restarting server '%s' in %s", serverName, backoff)
+		if err := register(serverName, 0, time.Time{}, supervisorStart); err != nil {
+			logf("Warning: Failed to save active servers: %v", err)
+		}
+		if waitForRestart(serverName, backoff) {
+			logf("Restart of server '%s' was cancelled", serverName)
+			unregister(serverName)
+			return nil
+		}
+
+		restarts = append(restarts, time.Now())
+		backoff *= 2
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// runServer starts the server's JVM, records it as active and waits for it to exit.
+// The server output goes to logs/server.log and, if con is not nil, to the attached clients.
+func runServer(serverConfig config.ServerConfig, supervisorStart time.Time, con *console) (*os.ProcessState, error) {
+	// Build the java command from the server configuration
+	cmd, err := process.JavaCommand(serverConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create log file for the server
+	logDir := filepath.Join(serverConfig.Path, "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create logs directory: %w", err)
+	}
+
+	logFile, err := os.OpenFile(
+		filepath.Join(logDir, "server.log"),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
+		0644,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file: %w", err)
+	}
+	defer logFile.Close()
+
+	// Set the output files and process attributes
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = process.NewSysProcAttr()
+
+	// Connect the server to the console, keeping its stdin open for attached clients
+	if con != nil {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+		}
+		output := io.MultiWriter(logFile, con)
+		cmd.Stdout = output
+		cmd.Stderr = output
+
+		con.setStdin(stdin)
+		defer con.setStdin(nil)
+	}
+
+	// Start the server process
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+
+	// Record the exact PID and start time of the JVM
+	javaPID := cmd.Process.Pid
+	startTime, err := process.ProcessStartTime(javaPID)
+	if err != nil {
+		logf("Warning: Failed to get process start time: %v", err)
+	}
+	if err := register(serverConfig.Name, javaPID, startTime, supervisorStart); err != nil {
+		logf("Warning: Failed to save active servers: %v", err)
+	}
+	logf("Started server '%s' with Java process PID %d", serverConfig.Name, javaPID)
+
+	// Update the server configuration with the last started time
+	err = config.ModifyServer(serverConfig.Name, func(server *config.ServerConfig) error {
+		server.LastStarted = time.Now()
+		return nil
+	})
+	if err != nil {
+		logf("Warning: Failed to update server configuration: %v", err)
+	}
+
+	// Wait for the server to exit. A non-zero exit is reported through the process state.
+	if err := cmd.Wait(); err != nil && cmd.ProcessState == nil {
+		return nil, fmt.Errorf("failed to wait for server: %w", err)
+	}
+
+	return cmd.ProcessState, nil
+}
+
+// register records the server as active with its current JVM PID.
+// A PID of 0 means the supervisor is waiting to restart the server.
+func register(serverName string, pid int, startTime, supervisorStart time.Time) error {
+	return process.UpdateActiveServers(func() error {
+		process.ActiveServers[serverName] = &process.ServerProcess{
+			Name:                serverName,
+			PID:                 pid,
+			Running:             pid != 0,
+			StartTime:           startTime,
+			SupervisorPID:       os.Getpid(),
+			SupervisorStartTime: supervisorStart,
+		}
+		return nil
+	})
+}
+
+// unregister removes the server from the active servers
+func unregister(serverName string) {
+	err := process.UpdateActiveServers(func() error {
+		// Only remove the entry if it still belongs to this supervisor
+		if proc, exists := process.ActiveServers[serverName]; exists && proc.SupervisorPID == os.Getpid() {
+			delete(process.ActiveServers, serverName)
+		}
+		return nil
+	})
+	if err != nil {
+		logf("Warning: Failed to save active servers: %v", err)
+	}
+}
+
+// stopRequested checks if the server was stopped through mcsrvr, which either marks
+// the active server entry or removes it
+func stopRequested(serverName string) bool {
+	if err := process.ReloadActiveServers(); err != nil {
+		logf("Warning: Failed to load active servers: %v", err)
+		return false
+	}
+
+	proc, exists := process.GetActiveServer(serverName)
+	return !exists || proc.SupervisorPID != os.Getpid() || proc.StopRequested
+}
+
+// waitForRestart sleeps for the backoff delay and reports whether a stop was requested meanwhile
+func waitForRestart(serverName string, backoff time.Duration) bool {
+	deadline := time.Now().Add(backoff)
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		if stopRequested(serverName) {
+			return true
+		}
+	}
+	return false
+}
+
+// logf prints a timestamped supervisor message
+func logf(format string, args ...interface{}) {
+	fmt.Printf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+	serverInit "github.com/0v3rr1de0/mcsrvr/pkg/server/init"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
+)
+
+// UpgradeLatestBuild is the upgrade target that selects the latest build of the version a
+// server is already on
+const UpgradeLatestBuild = "latest-build"
+
+// previousJarSuffix is appended to the current jar when an upgrade installs a jar of the same name
+const previousJarSuffix = ".previous"
+
+// UpgradeServer installs another version or build of a server's software in place. The target
+// is a version, "latest" or UpgradeLatestBuild; an empty build selects the latest build of the
+// target version. The server is backed up first, and its current installation is kept so the
+// upgrade can be rolled back. A running server is only upgraded if restart is set, in which
+// case it is stopped for the upgrade and started again afterwards.
+func UpgradeServer(serverName, target, build, backupPath string, restart bool) (err error) {
+	// Get the server configuration
+	serverConfig, err := config.GetServer(serverName)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the new version before anything is changed
+	version := target
+	if target == UpgradeLatestBuild {
+		version = serverConfig.Version
+	}
+	download, err := downloader.Resolve(serverConfig.Type, version, build)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", target, err)
+	}
+	if download.Version == serverConfig.Version && download.Build == serverConfig.Build {
+		fmt.Printf("Server '%s' is already on %s\n", serverName, describeInstallation(download.Version, download.Build))
+		return nil
+	}
+
+	current, err := currentInstallation(serverConfig)
+	if err != nil {
+		return err
+	}
+
+	wasRunning, err := stopForUpgrade(serverName, restart)
+	if err != nil {
+		return err
+	}
+	defer startAfterUpgrade(serverName, wasRunning, &err)
+
+	fmt.Printf("Upgrading server '%s' from %s to %s\n", serverName,
+		describeInstallation(current.Version, current.Build), describeInstallation(download.Version, download.Build))
+
+	// Back up the server so the world can be restored if the new version breaks it
+	fmt.Println("Creating a backup before the upgrade...")
+	if err := CreateBackup(serverName, backupPath); err != nil {
+		return fmt.Errorf("failed to back up server before the upgrade: %w", err)
+	}
+
+	// Set the current jar aside, so an installation that writes a jar of the same name
+	// cannot overwrite it
+	currentJarPath := filepath.Join(serverConfig.Path, current.Jar)
+	asidePath := currentJarPath + previousJarSuffix
+	if current.Jar != "" {
+		if err := os.Rename(currentJarPath, asidePath); err != nil {
+			return fmt.Errorf("failed to set aside the current server jar: %w", err)
+		}
+	}
+
+	// Install the new version
+	launch, err := downloader.InstallDownload(download, serverConfig.Path, serverConfig.JavaPath)
+	if err != nil {
+		if current.Jar != "" {
+			os.Rename(asidePath, currentJarPath)
+		}
+		return fmt.Errorf("failed to install %s: %w", describeInstallation(download.Version, download.Build), err)
+	}
+
+	// Put the current jar back unless the new one took its name
+	previous := current
+	if current.Jar != "" {
+		if launch.Jar == current.Jar {
+			previous.Jar = current.Jar + previousJarSuffix
+		} else if err := os.Rename(asidePath, currentJarPath); err != nil {
+			return fmt.Errorf("failed to restore the previous server jar: %w", err)
+		}
+	}
+
+	// Only the installation before this one is kept for rollbacks
+	if old := serverConfig.Previous; old != nil && old.Jar != "" &&
+		old.Jar != current.Jar && old.Jar != previous.Jar && old.Jar != launch.Jar {
+		os.Remove(filepath.Join(serverConfig.Path, old.Jar))
+	}
+
+	// Point the startup scripts at the new installation
+	currentLaunch := &downloader.Launch{Jar: current.Jar, ArgsFiles: current.ArgsFiles}
+	if err := serverInit.UpdateStartupScript(serverConfig.Path, currentLaunch, launch); err != nil {
+		fmt.Printf("Warning: Failed to update the startup script: %v\n", err)
+	}
+
+	// Record the new installation
+	err = config.ModifyServer(serverName, func(server *config.ServerConfig) error {
+		server.Version = download.Version
+		server.Build = download.Build
+		server.Jar = launch.Jar
+		server.ArgsFiles = launch.ArgsFiles
+		server.Previous = &previous
+		return nil
+	})
+	if err != nil {
+		// Go back to the current installation, which the configuration still describes
+		if current.Jar != "" && launch.Jar == current.Jar {
+			os.Rename(asidePath, currentJarPath)
+		}
+		serverInit.UpdateStartupScript(serverConfig.Path, launch, currentLaunch)
+		return fmt.Errorf("failed to update server configuration: %w", err)
+	}
+
+	fmt.Printf("Server '%s' upgraded to %s\n", serverName, describeInstallation(download.Version, download.Build))
+	fmt.Printf("To go back to %s, run: mcsrvr upgrade %s --rollback\n", describeInstallation(previous.Version, previous.Build), serverName)
+
+	return nil
+}
+
+// RollbackServer switches a server back to the installation it had before its last upgrade.
+// Rolling back again returns to the upgraded installation. Only the server software is rolled
+// back; a world converted by a newer Minecraft version has to be restored from the backup
+// taken before the upgrade.
+func RollbackServer(serverName string, restart bool) (err error) {
+	// Get the server configuration
+	serverConfig, err := config.GetServer(serverName)
+	if err != nil {
+		return err
+	}
+	if serverConfig.Previous == nil {
+		return fmt.Errorf("server '%s' has not been upgraded, so there is nothing to roll back", serverName)
+	}
+
+	current, err := currentInstallation(serverConfig)
+	if err != nil {
+		return err
+	}
+	previous := *serverConfig.Previous
+
+	// Make sure the previous installation is still there
+	for _, file := range append([]string{previous.Jar}, previous.ArgsFiles...) {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(serverConfig.Path, file)); err != nil {
+			return fmt.Errorf("the previous installation is incomplete, restore the backup taken before the upgrade instead: %w", err)
+		}
+	}
+
+	wasRunning, err := stopForUpgrade(serverName, restart)
+	if err != nil {
+		return err
+	}
+	defer startAfterUpgrade(serverName, wasRunning, &err)
+
+	fmt.Printf("Rolling server '%s' back from %s to %s\n", serverName,
+		describeInstallation(current.Version, current.Build), describeInstallation(previous.Version, previous.Build))
+
+	// A jar that was set aside because the upgrade reused its name is swapped back into place
+	currentLaunch := &downloader.Launch{Jar: current.Jar, ArgsFiles: current.ArgsFiles}
+	if current.Jar != "" && previous.Jar == current.Jar+previousJarSuffix {
+		jarPath := filepath.Join(serverConfig.Path, current.Jar)
+		swapPath := jarPath + ".rollback"
+		if err := os.Rename(jarPath, swapPath); err != nil {
+			return fmt.Errorf("failed to swap server jars: %w", err)
+		}
+		if err := os.Rename(jarPath+previousJarSuffix, jarPath); err != nil {
+			os.Rename(swapPath, jarPath)
+			return fmt.Errorf("failed to swap server jars: %w", err)
+		}
+		if err := os.Rename(swapPath, jarPath+previousJarSuffix); err != nil {
+			return fmt.Errorf("failed to swap server jars: %w", err)
+		}
+		previous.Jar = current.Jar
+		current.Jar = current.Jar + previousJarSuffix
+	}
+
+	// Point the startup scripts at the previous installation
+	previousLaunch := &downloader.Launch{Jar: previous.Jar, ArgsFiles: previous.ArgsFiles}
+	if err := serverInit.UpdateStartupScript(serverConfig.Path, currentLaunch, previousLaunch); err != nil {
+		fmt.Printf("Warning: Failed to update the startup script: %v\n", err)
+	}
+
+	// Swap the installations in the configuration
+	err = config.ModifyServer(serverName, func(server *config.ServerConfig) error {
+		server.Version = previous.Version
+		server.Build = previous.Build
+		server.Jar = previous.Jar
+		server.ArgsFiles = previous.ArgsFiles
+		server.Previous = &current
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update server configuration: %w", err)
+	}
+
+	fmt.Printf("Server '%s' rolled back to %s\n", serverName, describeInstallation(previous.Version, previous.Build))
+	if previous.Version != current.Version {
+		fmt.Println("Note: The world is not rolled back. If the newer version converted it, restore the backup taken before the upgrade.")
+	}
+
+	return nil
+}
+
+// currentInstallation returns the installation a server is configured with. Servers created
+// before the jar was stored in the configuration have it detected from their startup script.
+func currentInstallation(serverConfig config.ServerConfig) (config.Installation, error) {
+	installation := config.Installation{
+		Version:   serverConfig.Version,
+		Build:     serverConfig.Build,
+		Jar:       serverConfig.Jar,
+		ArgsFiles: serverConfig.ArgsFiles,
+	}
+	if installation.Jar == "" && len(installation.ArgsFiles) == 0 {
+		jar, err := process.FindServerJar(serverConfig.Path)
+		if err != nil {
+			return config.Installation{}, err
+		}
+		installation.Jar = jar
+	}
+	return installation, nil
+}
+
+// stopForUpgrade stops a running server if restart is set, and refuses to continue otherwise.
+// It reports whether the server was running.
+func stopForUpgrade(serverName string, restart bool) (bool, error) {
+	if _, exists := process.GetActiveServer(serverName); !exists {
+		return false, nil
+	}
+	if !restart {
+		return false, fmt.Errorf("server '%s' is running, stop it first or use --restart", serverName)
+	}
+
+	if err := StopServer(serverName, DefaultStopOptions()); err != nil {
+		return false, fmt.Errorf("failed to stop server: %w", err)
+	}
+	return true, nil
+}
+
+// startAfterUpgrade starts a server again if it was stopped for an upgrade or rollback. It is
+// deferred as soon as the server is stopped, so a production server is started again even if
+// the upgrade fails. A failure to start it is added to the error in err.
+func startAfterUpgrade(serverName string, wasRunning bool, err *error) {
+	if !wasRunning {
+		return
+	}
+
+	if *err != nil {
+		fmt.Printf("Starting server '%s' again after the failure...\n", serverName)
+	} else {
+		fmt.Printf("Starting server '%s'...\n", serverName)
+	}
+	if startErr := StartServer(serverName); startErr != nil {
+		if *err != nil {
+			*err = fmt.Errorf("%w, and failed to start server again: %v", *err, startErr)
+		} else {
+			*err = fmt.Errorf("failed to start server: %w", startErr)
+		}
+	}
+}
+
+// describeInstallation describes a version and build of the server software
+func describeInstallation(version, build string) string {
+	if build == "" {
+		return version
+	}
+	return fmt.Sprintf("%s build %s", version, build)
+}
@@ -0,0 +1,507 @@
+package spec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+	"github.com/0v3rr1de0/mcsrvr/pkg/properties"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server"
+	serverInit "github.com/0v3rr1de0/mcsrvr/pkg/server/init"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/process"
+)
+
+// Step is one change that converges a server to its spec
+type Step struct {
+	// Description is shown in the plan. It starts with + for something that is added,
+	// - for something that is removed and ~ for something that is changed.
+	Description string
+	apply       func(options Options) error
+}
+
+// Options control how a plan is applied
+type Options struct {
+	// BackupPath is the backup repository used for the backup before an upgrade
+	BackupPath string
+	// Restart allows changes to a running server, which is stopped for them and started again
+	Restart bool
+}
+
+// Plan compares a spec with the server it describes, its configuration and the files in its
+// directory, and returns the steps that converge the server to the spec. An up-to-date server
+// has no steps. Nothing is changed.
+func Plan(s *Spec) ([]Step, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	serverConfig, exists := cfg.Servers[s.Name]
+	if exists {
+		if serverConfig.Type != s.Type {
+			return nil, fmt.Errorf("server '%s' is a %s server, the type of a server cannot be changed", s.Name, serverConfig.Type)
+		}
+		if s.Path != "" && filepath.Clean(serverConfig.Path) != s.Path {
+			return nil, fmt.Errorf("server '%s' is at %s, a server cannot be moved", s.Name, serverConfig.Path)
+		}
+
+		upgrade, err := planUpgrade(s, serverConfig)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, upgrade...)
+		steps = append(steps, planSettings(s, serverConfig)...)
+	} else {
+		if s.Path == "" {
+			return nil, fmt.Errorf("server '%s' does not exist, and the spec has no path to create it at", s.Name)
+		}
+		steps = append(steps, planCreate(s))
+		serverConfig = config.ServerConfig{Name: s.Name, Type: s.Type, Path: s.Path}
+	}
+
+	files, err := planFiles(s, serverConfig)
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, files...)
+	steps = append(steps, planSchedules(s, serverConfig)...)
+
+	if s.Backups != nil && s.Backups.retention() != serverConfig.Retention {
+		retention := s.Backups.retention()
+		steps = append(steps, Step{
+			Description: "~ backup retention policy",
+			apply: func(Options) error {
+				return updateServer(s.Name, func(c *config.ServerConfig) { c.Retention = retention })
+			},
+		})
+	}
+
+	return steps, nil
+}
+
+// Apply runs the steps of a plan. A running server is only changed if restart is set in the
+// options, in which case it is stopped before the first step and started again afterwards,
+// even if a step fails.
+func Apply(s *Spec, steps []Step, options Options) (err error) {
+	if proc, exists := process.GetActiveServer(s.Name); exists && proc.Running && len(steps) > 0 {
+		if !options.Restart {
+			return fmt.Errorf("server '%s' is running, stop it first or use --restart", s.Name)
+		}
+		if err := server.StopServer(s.Name, server.DefaultStopOptions()); err != nil {
+			return fmt.Errorf("failed to stop server: %w", err)
+		}
+		defer startAgain(s.Name, &err)
+	}
+
+	for _, step := range steps {
+		fmt.Println(step.Description)
+		if err := step.apply(options); err != nil {
+			return err
+		}
+	}
+
+	// Remember all plugins and mods of the spec, including the ones that were already in
+	// place, so dropping them removes them. Downloads and removals are recorded by their steps.
+	if s.Plugins != nil || s.Mods != nil {
+		addons := s.addonFiles()
+		if err := updateServer(s.Name, func(c *config.ServerConfig) { c.Addons = addons }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startAgain starts a server that Apply stopped. A failure to start it is added to the
+// error of Apply in err.
+func startAgain(serverName string, err *error) {
+	if *err != nil {
+		fmt.Printf("Starting server '%s' again after the failure...\n", serverName)
+	} else {
+		fmt.Printf("Starting server '%s'...\n", serverName)
+	}
+	if startErr := server.StartServer(serverName); startErr != nil {
+		if *err != nil {
+			*err = fmt.Errorf("%w, and failed to start server again: %v", *err, startErr)
+		} else {
+			*err = fmt.Errorf("failed to start server: %w", startErr)
+		}
+	}
+}
+
+// planCreate returns the step that initializes a server that does not exist yet
+func planCreate(s *Spec) Step {
+	return Step{
+		Description: fmt.Sprintf("+ create %s server '%s' %s at %s", s.Type, s.Name, describeVersion(s.Version, s.Build), s.Path),
+		apply: func(Options) error {
+			memory, javaArgs := s.Memory, s.JavaArgs
+			if memory == "" || javaArgs == "" {
+				defaults, err := config.GetDefaults()
+				if err != nil {
+					return fmt.Errorf("failed to get default configuration: %w", err)
+				}
+				if memory == "" {
+					memory = defaults.Memory
+				}
+				if javaArgs == "" {
+					javaArgs = defaults.JavaArgs
+				}
+			}
+			return server.InitializeServer(s.Path, s.Name, s.Type, s.Version, s.Build, memory, s.Java, javaArgs)
+		},
+	}
+}
+
+// planUpgrade returns the step that installs the version of the spec, if the server is on
+// another one. Without a build in the spec, any build of the version is up to date.
+func planUpgrade(s *Spec, serverConfig config.ServerConfig) ([]Step, error) {
+	if s.Version == serverConfig.Version && (s.Build == "" || s.Build == serverConfig.Build) {
+		return nil, nil
+	}
+
+	// Versions such as latest have to be resolved to know whether they are installed
+	download, err := downloader.Resolve(s.Type, s.Version, s.Build)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", s.Version, err)
+	}
+	if download.Version == serverConfig.Version && (s.Build == "" || download.Build == serverConfig.Build) {
+		return nil, nil
+	}
+
+	return []Step{{
+		Description: fmt.Sprintf("~ version: %s -> %s",
+			describeVersion(serverConfig.Version, serverConfig.Build), describeVersion(download.Version, download.Build)),
+		apply: func(options Options) error {
+			return server.UpgradeServer(s.Name, download.Version, download.Build, options.BackupPath, false)
+		},
+	}}, nil
+}
+
+// planSettings returns the steps that change how the server is started
+func planSettings(s *Spec, serverConfig config.ServerConfig) []Step {
+	var steps []Step
+	setting := func(name, current, desired string, set func(c *config.ServerConfig)) {
+		if desired == "" || desired == current {
+			return
+		}
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("~ %s: %q -> %q", name, current, desired),
+			apply: func(Options) error {
+				if err := updateServer(s.Name, set); err != nil {
+					return err
+				}
+				return rewriteStartupScript(s.Name)
+			},
+		})
+	}
+
+	setting("memory", serverConfig.Memory, s.Memory, func(c *config.ServerConfig) { c.Memory = s.Memory })
+	setting("javaArgs", serverConfig.JavaArgs, s.JavaArgs, func(c *config.ServerConfig) { c.JavaArgs = s.JavaArgs })
+	setting("java", serverConfig.JavaPath, s.Java, func(c *config.ServerConfig) { c.JavaPath = s.Java })
+	return steps
+}
+
+// planFiles returns the steps that change server.properties, ops.json, whitelist.json and
+// the plugins and mods
+func planFiles(s *Spec, serverConfig config.ServerConfig) ([]Step, error) {
+	var steps []Step
+	serverPath := serverConfig.Path
+	propertiesPath := filepath.Join(serverPath, "server.properties")
+
+	// Set the properties in a stable order
+	if len(s.Properties) > 0 {
+		props, err := properties.Load(propertiesPath)
+		if os.IsNotExist(err) {
+			props = properties.New()
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read server.properties: %w", err)
+		}
+
+		keys := make([]string, 0, len(s.Properties))
+		for key := range s.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			desired := s.Properties[key]
+			current, ok := props.Get(key)
+			if ok && current == desired {
+				continue
+			}
+			description := fmt.Sprintf("+ property %s=%s", key, desired)
+			if ok {
+				description = fmt.Sprintf("~ property %s: %q -> %q", key, current, desired)
+			}
+			steps = append(steps, Step{
+				Description: description,
+				apply: func(Options) error {
+					return setProperty(propertiesPath, key, desired)
+				},
+			})
+		}
+	}
+
+	// Ops and whitelisted players
+	for _, list := range []struct {
+		players playerList
+		names   []string
+		label   string
+	}{
+		{opsList, s.Ops, "op"},
+		{whitelistList, s.Whitelist, "whitelisted player"},
+	} {
+		if list.names == nil {
+			continue
+		}
+		current, err := list.players.load(serverPath)
+		if err != nil {
+			return nil, err
+		}
+		added, removed := diffPlayers(current, list.names)
+		players := list.players
+		for _, name := range removed {
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("- %s %s", list.label, name),
+				apply:       func(Options) error { return players.remove(serverPath, name) },
+			})
+		}
+		for _, name := range added {
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("+ %s %s", list.label, name),
+				apply:       func(Options) error { return players.add(serverPath, name) },
+			})
+		}
+	}
+
+	// Plugins and mods
+	if s.Plugins != nil || s.Mods != nil {
+		addonSteps, err := planAddons(s, serverConfig)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, addonSteps...)
+	}
+
+	return steps, nil
+}
+
+// planAddons returns the steps that download missing or changed plugins and mods, and remove
+// the ones an earlier apply installed that are no longer in the spec
+func planAddons(s *Spec, serverConfig config.ServerConfig) ([]Step, error) {
+	var steps []Step
+	dir := downloader.AddonDir(s.Type)
+	kind := "plugin"
+	if dir == "mods" {
+		kind = "mod"
+	}
+
+	wanted := make(map[string]bool)
+	for _, file := range s.addonFiles() {
+		wanted[file] = true
+	}
+	for _, file := range serverConfig.Addons {
+		if wanted[file] {
+			continue
+		}
+		filePath := filepath.Join(serverConfig.Path, file)
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			continue
+		}
+		steps = append(steps, Step{
+			Description: fmt.Sprintf("- %s %s", kind, filepath.Base(file)),
+			apply: func(Options) error {
+				if err := os.Remove(filePath); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", filepath.Base(file), err)
+				}
+				return updateServer(s.Name, func(c *config.ServerConfig) { c.Addons = withoutAddon(c.Addons, file) })
+			},
+		})
+	}
+
+	for _, addon := range append(append([]Addon{}, s.Plugins...), s.Mods...) {
+		file := filepath.Join(dir, addon.File)
+		filePath := filepath.Join(serverConfig.Path, file)
+		checksum := addon.checksum()
+
+		// A jar that is present is kept unless it no longer matches its checksum
+		description := fmt.Sprintf("+ %s %s", kind, addon.File)
+		if _, err := os.Stat(filePath); err == nil {
+			if checksum.Algorithm == "" || downloader.VerifyFile(filePath, checksum) == nil {
+				continue
+			}
+			description = fmt.Sprintf("~ %s %s (checksum changed)", kind, addon.File)
+		}
+
+		steps = append(steps, Step{
+			Description: description,
+			apply: func(Options) error {
+				if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+					return fmt.Errorf("failed to create %s directory: %w", dir, err)
+				}
+				if err := downloader.DownloadFile(addon.URL, filePath, checksum); err != nil {
+					return fmt.Errorf("failed to download %s: %w", addon.File, err)
+				}
+
+				// Record the file right away, so it is removed when it is dropped from the
+				// spec even if a later step of this apply fails
+				return updateServer(s.Name, func(c *config.ServerConfig) {
+					c.Addons = append(withoutAddon(c.Addons, file), file)
+				})
+			},
+		})
+	}
+
+	return steps, nil
+}
+
+// planSchedules returns the steps that add, change and remove scheduled tasks
+func planSchedules(s *Spec, serverConfig config.ServerConfig) []Step {
+	if s.Schedules == nil {
+		return nil
+	}
+
+	var steps []Step
+	desired := make(map[string]config.Schedule)
+	for _, schedule := range s.Schedules {
+		desired[schedule.Name] = schedule.config()
+	}
+
+	current := make(map[string]config.Schedule)
+	for _, schedule := range serverConfig.Schedules {
+		current[schedule.Name] = schedule
+		if _, ok := desired[schedule.Name]; !ok {
+			name := schedule.Name
+			steps = append(steps, Step{
+				Description: fmt.Sprintf("- schedule %s", name),
+				apply: func(Options) error {
+					return updateServer(s.Name, func(c *config.ServerConfig) { c.Schedules = withoutSchedule(c.Schedules, name) })
+				},
+			})
+		}
+	}
+
+	for _, spec := range s.Schedules {
+		schedule := spec.config()
+		existing, ok := current[schedule.Name]
+		if ok && reflect.DeepEqual(normalizeSchedule(existing), normalizeSchedule(schedule)) {
+			continue
+		}
+		description := fmt.Sprintf("+ schedule %s (%s, %s)", schedule.Name, schedule.Cron, schedule.Action)
+		if ok {
+			description = fmt.Sprintf("~ schedule %s (%s, %s)", schedule.Name, schedule.Cron, schedule.Action)
+		}
+		steps = append(steps, Step{
+			Description: description,
+			apply: func(Options) error {
+				return updateServer(s.Name, func(c *config.ServerConfig) {
+					c.Schedules = append(withoutSchedule(c.Schedules, schedule.Name), schedule)
+				})
+			},
+		})
+	}
+
+	return steps
+}
+
+// withoutSchedule returns the schedules without the one with the given name
+func withoutSchedule(schedules []config.Schedule, name string) []config.Schedule {
+	result := make([]config.Schedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		if schedule.Name != name {
+			result = append(result, schedule)
+		}
+	}
+	return result
+}
+
+// withoutAddon returns the addon files without the given one
+func withoutAddon(addons []string, file string) []string {
+	result := make([]string, 0, len(addons))
+	for _, addon := range addons {
+		if addon != file {
+			result = append(result, addon)
+		}
+	}
+	return result
+}
+
+// normalizeSchedule makes schedules without warnings compare equal however they were written
+func normalizeSchedule(schedule config.Schedule) config.Schedule {
+	if len(schedule.Warnings) == 0 {
+		schedule.Warnings = nil
+	}
+	return schedule
+}
+
+// addonFiles returns the plugin and mod files of the spec, relative to the server directory
+func (s *Spec) addonFiles() []string {
+	dir := downloader.AddonDir(s.Type)
+	var files []string
+	for _, addon := range append(append([]Addon{}, s.Plugins...), s.Mods...) {
+		files = append(files, filepath.Join(dir, addon.File))
+	}
+	return files
+}
+
+// setProperty sets one value in a server.properties file
+func setProperty(propertiesPath, key, value string) error {
+	props, err := properties.Load(propertiesPath)
+	if os.IsNotExist(err) {
+		props = properties.New()
+	} else if err != nil {
+		return fmt.Errorf("failed to read server.properties: %w", err)
+	}
+
+	props.Set(key, value)
+	if err := props.Save(propertiesPath); err != nil {
+		return fmt.Errorf("failed to write server.properties: %w", err)
+	}
+	return nil
+}
+
+// updateServer changes the configuration of a server
+func updateServer(serverName string, update func(c *config.ServerConfig)) error {
+	err := config.ModifyServer(serverName, func(serverConfig *config.ServerConfig) error {
+		update(serverConfig)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update server configuration: %w", err)
+	}
+	return nil
+}
+
+// rewriteStartupScript writes the startup script of a server again after its memory or
+// Java settings changed. The server itself is started from its configuration, so the
+// script only matters for starting it by hand.
+func rewriteStartupScript(serverName string) error {
+	serverConfig, err := config.GetServer(serverName)
+	if err != nil {
+		return err
+	}
+
+	launch := &downloader.Launch{Jar: serverConfig.Jar, ArgsFiles: serverConfig.ArgsFiles}
+	if launch.Jar == "" && len(launch.ArgsFiles) == 0 {
+		if launch.Jar, err = process.FindServerJar(serverConfig.Path); err != nil {
+			return err
+		}
+	}
+
+	_, err = serverInit.CreateStartupScript(serverConfig.Path, launch, serverName, serverConfig.Memory,
+		serverConfig.JavaPath, serverConfig.JavaArgs, downloader.IsProxy(serverConfig.Type))
+	return err
+}
+
+// describeVersion describes a version and build of the server software
+func describeVersion(version, build string) string {
+	if build == "" {
+		return version
+	}
+	return fmt.Sprintf("%s build %s", version, build)
+}
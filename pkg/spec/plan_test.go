@@ -0,0 +1,283 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+)
+
+// testServer is the name of the server the tests converge
+const testServer = "test"
+
+// pluginJars are the plugins served by the fake plugin repository
+var pluginJars = map[string][]byte{
+	"checked.jar":   []byte("a plugin with a checksum"),
+	"unchecked.jar": []byte("a plugin without a checksum"),
+}
+
+// setupServer creates an mcsrvr home with an existing Paper server, and a fake plugin
+// repository. It returns the server directory and the URL of the repository.
+func setupServer(t *testing.T) (string, string) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv(config.HomeEnv, home)
+	if err := config.Initialize(config.Options{Home: home}); err != nil {
+		t.Fatalf("failed to initialize config: %v", err)
+	}
+
+	serverPath := filepath.Join(home, "server")
+	if err := os.MkdirAll(serverPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	initial := "#Minecraft server properties\nonline-mode=false\nmotd=A Minecraft Server\nview-distance=10\n"
+	if err := os.WriteFile(filepath.Join(serverPath, "server.properties"), []byte(initial), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := config.AddServer(config.ServerConfig{
+		Name:     testServer,
+		Type:     "papermc",
+		Version:  "1.21.4",
+		Build:    "100",
+		Path:     serverPath,
+		Memory:   "2G",
+		JavaArgs: "-XX:+UseG1GC",
+	})
+	if err != nil {
+		t.Fatalf("failed to add server: %v", err)
+	}
+
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jar, exists := pluginJars[strings.TrimPrefix(r.URL.Path, "/")]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(jar)
+	}))
+	t.Cleanup(repo.Close)
+
+	return serverPath, repo.URL
+}
+
+// loadSpec writes a spec file into the server directory and loads it
+func loadSpec(t *testing.T, serverPath, yaml string) *Spec {
+	t.Helper()
+	specPath := filepath.Join(serverPath, "server.yaml")
+	if err := os.WriteFile(specPath, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(specPath)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	return s
+}
+
+// planSteps plans a spec and returns the descriptions of its steps
+func planSteps(t *testing.T, s *Spec) ([]Step, []string) {
+	t.Helper()
+	steps, err := Plan(s)
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+	var descriptions []string
+	for _, step := range steps {
+		descriptions = append(descriptions, step.Description)
+	}
+	return steps, descriptions
+}
+
+// sha256Hex returns the SHA-256 of data in hexadecimal
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fullSpec is a spec that manages every section a Paper server has
+const fullSpec = `name: test
+type: papermc
+version: "1.21.4"
+build: "100"
+memory: 2G
+javaArgs: -XX:+UseG1GC
+properties:
+  motd: Welcome
+  max-players: "50"
+  online-mode: "false"
+ops: [Alice]
+whitelist: [Alice, Bob]
+plugins:
+  - url: {{repo}}/checked.jar
+    sha256: {{sha256}}
+  - url: {{repo}}/unchecked.jar
+schedules:
+  - name: nightly-backup
+    cron: "0 4 * * *"
+    action: backup
+    warnings: []
+  - name: daily-restart
+    cron: "@daily"
+    action: restart
+    warnings: [5m, 1m]
+  - name: announce
+    cron: "0 * * * *"
+    action: command
+    command: say hello
+backups:
+  keepLast: 5
+  keepDaily: 7
+`
+
+// expandSpec fills in the URL of the plugin repository and the checksum of the checked plugin
+func expandSpec(spec, repoURL string) string {
+	return strings.NewReplacer("{{repo}}", repoURL, "{{sha256}}", sha256Hex(pluginJars["checked.jar"])).Replace(spec)
+}
+
+func TestApplyConverges(t *testing.T) {
+	serverPath, repoURL := setupServer(t)
+	s := loadSpec(t, serverPath, expandSpec(fullSpec, repoURL))
+
+	steps, descriptions := planSteps(t, s)
+	if len(steps) == 0 {
+		t.Fatal("Plan returned no steps for a server that differs from its spec")
+	}
+	if err := Apply(s, steps, Options{}); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	// Reapplying the unchanged spec changes nothing
+	if _, again := planSteps(t, loadSpec(t, serverPath, expandSpec(fullSpec, repoURL))); len(again) != 0 {
+		t.Errorf("Plan after Apply returned %d steps, want none: %q (first plan: %q)", len(again), again, descriptions)
+	}
+
+	// Untouched properties and comments are kept
+	data, err := os.ReadFile(filepath.Join(serverPath, "server.properties"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#Minecraft server properties\nonline-mode=false\nmotd=Welcome\nview-distance=10\nmax-players=50\n"
+	if string(data) != want {
+		t.Errorf("server.properties = %q, want %q", data, want)
+	}
+
+	// Players get the UUIDs of offline mode, without looking them up
+	for _, list := range []playerList{opsList, whitelistList} {
+		players, err := list.load(serverPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i := indexOfPlayer(players, "Alice"); i < 0 || players[i].UUID != offlineUUID("Alice") {
+			t.Errorf("%s does not contain Alice with the offline UUID of the name: %+v", list.file, players)
+		}
+	}
+	if got := offlineUUID("Notch"); got != "b50ad385-829d-3141-a216-7e7d7539ba7f" {
+		t.Errorf("offlineUUID(Notch) = %s, want b50ad385-829d-3141-a216-7e7d7539ba7f", got)
+	}
+
+	serverConfig, err := config.GetServer(testServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serverConfig.Schedules) != 3 {
+		t.Errorf("config has %d schedules, want 3", len(serverConfig.Schedules))
+	}
+	if serverConfig.Retention != (config.Retention{KeepLast: 5, KeepDaily: 7}) {
+		t.Errorf("retention = %+v, want keepLast 5 and keepDaily 7", serverConfig.Retention)
+	}
+	wantAddons := []string{filepath.Join("plugins", "checked.jar"), filepath.Join("plugins", "unchecked.jar")}
+	if strings.Join(serverConfig.Addons, ",") != strings.Join(wantAddons, ",") {
+		t.Errorf("addons = %q, want %q", serverConfig.Addons, wantAddons)
+	}
+}
+
+func TestPlanDetectsDrift(t *testing.T) {
+	serverPath, repoURL := setupServer(t)
+	spec := expandSpec(fullSpec, repoURL)
+	s := loadSpec(t, serverPath, spec)
+
+	steps, _ := planSteps(t, s)
+	if err := Apply(s, steps, Options{}); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	// A jar with a checksum is replaced when it changes on disk, one without is left alone
+	for _, file := range []string{"checked.jar", "unchecked.jar"} {
+		if err := os.WriteFile(filepath.Join(serverPath, "plugins", file), []byte("changed"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, descriptions := planSteps(t, loadSpec(t, serverPath, spec))
+	if want := []string{"~ plugin checked.jar (checksum changed)"}; strings.Join(descriptions, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Plan = %q, want %q", descriptions, want)
+	}
+
+	// Dropped players, plugins and schedules are removed
+	reduced := strings.NewReplacer(
+		"whitelist: [Alice, Bob]", "whitelist: [Alice]",
+		"  - url: "+repoURL+"/unchecked.jar\n", "",
+		"  - name: announce\n    cron: \"0 * * * *\"\n    action: command\n    command: say hello\n", "",
+	).Replace(spec)
+	_, descriptions = planSteps(t, loadSpec(t, serverPath, reduced))
+	want := []string{
+		"- whitelisted player Bob",
+		"- plugin unchecked.jar",
+		"~ plugin checked.jar (checksum changed)",
+		"- schedule announce",
+	}
+	if strings.Join(descriptions, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Plan = %q, want %q", descriptions, want)
+	}
+}
+
+func TestApplyRecordsAddonsOnFailure(t *testing.T) {
+	serverPath, repoURL := setupServer(t)
+
+	// The second plugin does not match its checksum, so Apply fails after the first was downloaded
+	spec := expandSpec(`name: test
+type: papermc
+version: "1.21.4"
+build: "100"
+plugins:
+  - url: {{repo}}/unchecked.jar
+  - url: {{repo}}/checked.jar
+    sha256: 0000000000000000000000000000000000000000000000000000000000000000
+`, repoURL)
+	s := loadSpec(t, serverPath, spec)
+	steps, _ := planSteps(t, s)
+	if err := Apply(s, steps, Options{}); err == nil {
+		t.Fatal("Apply of a plugin with the wrong checksum returned no error")
+	}
+
+	serverConfig, err := config.GetServer(testServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("plugins", "unchecked.jar"); len(serverConfig.Addons) != 1 || serverConfig.Addons[0] != want {
+		t.Fatalf("addons = %q, want [%s]", serverConfig.Addons, want)
+	}
+
+	// Dropping the downloaded plugin from the spec removes it
+	s = loadSpec(t, serverPath, "name: test\ntype: papermc\nversion: \"1.21.4\"\nbuild: \"100\"\nplugins: []\n")
+	steps, descriptions := planSteps(t, s)
+	if want := []string{"- plugin unchecked.jar"}; strings.Join(descriptions, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Plan = %q, want %q", descriptions, want)
+	}
+	if err := Apply(s, steps, Options{}); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(serverPath, "plugins", "unchecked.jar")); !os.IsNotExist(err) {
+		t.Error("the dropped plugin was not removed")
+	}
+	if serverConfig, err = config.GetServer(testServer); err != nil || len(serverConfig.Addons) != 0 {
+		t.Errorf("addons = %q, %v, want none", serverConfig.Addons, err)
+	}
+}
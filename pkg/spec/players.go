@@ -0,0 +1,201 @@
+package spec
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+	"github.com/0v3rr1de0/mcsrvr/pkg/properties"
+)
+
+// MojangProfileURL looks up the UUID of a player by name
+var MojangProfileURL = "https://api.mojang.com/users/profiles/minecraft/"
+
+// defaultOpLevel is the permission level of new ops if server.properties does not set op-permission-level
+const defaultOpLevel = 4
+
+// player is an entry of ops.json or whitelist.json. Level and BypassesPlayerLimit are
+// only used in ops.json.
+type player struct {
+	UUID                string `json:"uuid"`
+	Name                string `json:"name"`
+	Level               *int   `json:"level,omitempty"`
+	BypassesPlayerLimit *bool  `json:"bypassesPlayerLimit,omitempty"`
+}
+
+// playerList is ops.json or whitelist.json in a server directory
+type playerList struct {
+	file string
+	ops  bool
+}
+
+var (
+	opsList       = playerList{file: "ops.json", ops: true}
+	whitelistList = playerList{file: "whitelist.json"}
+)
+
+// load reads the players of a list. A missing file is an empty list.
+func (l playerList) load(serverPath string) ([]player, error) {
+	data, err := os.ReadFile(filepath.Join(serverPath, l.file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", l.file, err)
+	}
+
+	var players []player
+	if err := json.Unmarshal(data, &players); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", l.file, err)
+	}
+	return players, nil
+}
+
+// save writes the players of a list
+func (l playerList) save(serverPath string, players []player) error {
+	if players == nil {
+		players = []player{}
+	}
+	data, err := json.MarshalIndent(players, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", l.file, err)
+	}
+	if err := os.WriteFile(filepath.Join(serverPath, l.file), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.file, err)
+	}
+	return nil
+}
+
+// diffPlayers returns the names that have to be added to and removed from a list of players.
+// Player names are not case-sensitive.
+func diffPlayers(current []player, names []string) (added, removed []string) {
+	for _, name := range names {
+		if indexOfPlayer(current, name) < 0 {
+			added = append(added, name)
+		}
+	}
+	for _, p := range current {
+		found := false
+		for _, name := range names {
+			if strings.EqualFold(p.Name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, p.Name)
+		}
+	}
+	return added, removed
+}
+
+// indexOfPlayer returns the index of a player in a list, or -1
+func indexOfPlayer(players []player, name string) int {
+	for i, p := range players {
+		if strings.EqualFold(p.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// add adds a player to a list, looking up its UUID the way the server would
+func (l playerList) add(serverPath, name string) error {
+	players, err := l.load(serverPath)
+	if err != nil {
+		return err
+	}
+	if indexOfPlayer(players, name) >= 0 {
+		return nil
+	}
+
+	props, err := properties.Load(filepath.Join(serverPath, "server.properties"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read server.properties: %w", err)
+	}
+	if props == nil {
+		props = properties.New()
+	}
+
+	// Servers in offline mode identify players by a UUID derived from their name
+	entry := player{Name: name}
+	if online, _ := props.Bool("online-mode", true); online {
+		entry.UUID, entry.Name, err = lookupPlayer(name)
+		if err != nil {
+			return err
+		}
+	} else {
+		entry.UUID = offlineUUID(name)
+	}
+
+	if l.ops {
+		level, _ := props.Int("op-permission-level", defaultOpLevel)
+		bypass := false
+		entry.Level = &level
+		entry.BypassesPlayerLimit = &bypass
+	}
+
+	return l.save(serverPath, append(players, entry))
+}
+
+// remove removes a player from a list
+func (l playerList) remove(serverPath, name string) error {
+	players, err := l.load(serverPath)
+	if err != nil {
+		return err
+	}
+	i := indexOfPlayer(players, name)
+	if i < 0 {
+		return nil
+	}
+	return l.save(serverPath, append(players[:i], players[i+1:]...))
+}
+
+// lookupPlayer returns the UUID and the correctly capitalized name of a Minecraft account
+func lookupPlayer(name string) (string, string, error) {
+	resp, err := downloader.Get(MojangProfileURL + url.PathEscape(name))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up player %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNoContent {
+		return "", "", fmt.Errorf("player %s does not exist", name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to look up player %s: unexpected response: %s", name, resp.Status)
+	}
+
+	var profile struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return "", "", fmt.Errorf("failed to parse profile of player %s: %w", name, err)
+	}
+	if len(profile.ID) != 32 {
+		return "", "", fmt.Errorf("invalid UUID %q for player %s", profile.ID, name)
+	}
+	return formatUUID(profile.ID), profile.Name, nil
+}
+
+// offlineUUID returns the UUID an offline-mode server gives a player, a version 3 UUID
+// of "OfflinePlayer:<name>"
+func offlineUUID(name string) string {
+	sum := md5.Sum([]byte("OfflinePlayer:" + name))
+	sum[6] = sum[6]&0x0f | 0x30
+	sum[8] = sum[8]&0x3f | 0x80
+	return formatUUID(hex.EncodeToString(sum[:]))
+}
+
+// formatUUID adds the dashes to a UUID in hexadecimal
+func formatUUID(id string) string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s", id[0:8], id[8:12], id[12:16], id[16:20], id[20:32])
+}
@@ -0,0 +1,223 @@
+// Package spec describes servers declaratively in YAML files that can be kept in version
+// control, and converges servers to them.
+package spec
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/0v3rr1de0/mcsrvr/pkg/config"
+	"github.com/0v3rr1de0/mcsrvr/pkg/downloader"
+	"github.com/0v3rr1de0/mcsrvr/pkg/properties"
+	"github.com/0v3rr1de0/mcsrvr/pkg/scheduler"
+	"github.com/0v3rr1de0/mcsrvr/pkg/server/backup"
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the declarative definition of a server. Only name and type are required; sections
+// that are left out are not managed, so a spec can describe as much of a server as needed.
+type Spec struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// Version is a version or "latest". An empty Build accepts any build of the version,
+	// so a server is not upgraded just because a newer build was released.
+	Version  string `yaml:"version"`
+	Build    string `yaml:"build"`
+	Path     string `yaml:"path"`
+	Memory   string `yaml:"memory"`
+	JavaArgs string `yaml:"javaArgs"`
+	Java     string `yaml:"java"`
+	// Properties are set in server.properties; keys that are not listed keep their value
+	Properties map[string]string `yaml:"properties"`
+	// Ops and Whitelist list player names. They replace the players in ops.json and
+	// whitelist.json, so an empty list removes everyone.
+	Ops       []string   `yaml:"ops"`
+	Whitelist []string   `yaml:"whitelist"`
+	Plugins   []Addon    `yaml:"plugins"`
+	Mods      []Addon    `yaml:"mods"`
+	Schedules []Schedule `yaml:"schedules"`
+	Backups   *Backups   `yaml:"backups"`
+}
+
+// Addon is a plugin or mod jar downloaded into the server
+type Addon struct {
+	URL string `yaml:"url"`
+	// File is the name of the jar, by default the last element of the URL
+	File string `yaml:"file"`
+	// SHA256 or SHA1 verify the download and detect a changed jar on disk
+	SHA256 string `yaml:"sha256"`
+	SHA1   string `yaml:"sha1"`
+}
+
+// Schedule is a recurring task run by the mcsrvr daemon, see 'mcsrvr schedule'
+type Schedule struct {
+	Name     string   `yaml:"name"`
+	Cron     string   `yaml:"cron"`
+	Action   string   `yaml:"action"`
+	Command  string   `yaml:"command"`
+	Warnings []string `yaml:"warnings"`
+}
+
+// Backups is the backup retention policy of a server, see 'mcsrvr config <server> retention'
+type Backups struct {
+	KeepLast     int    `yaml:"keepLast"`
+	KeepDaily    int    `yaml:"keepDaily"`
+	KeepWeekly   int    `yaml:"keepWeekly"`
+	MaxTotalSize string `yaml:"maxTotalSize"`
+}
+
+// Load reads and validates a spec file. A relative server path is relative to the directory
+// of the spec file.
+func Load(specPath string) (*Spec, error) {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec file: %w", err)
+	}
+
+	// Unknown fields are rejected, so a typo does not silently leave something unmanaged
+	var s Spec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse spec file: %w", err)
+	}
+
+	if s.Path != "" && !filepath.IsAbs(s.Path) {
+		s.Path = filepath.Join(filepath.Dir(specPath), s.Path)
+	}
+	if s.Path != "" {
+		if s.Path, err = filepath.Abs(s.Path); err != nil {
+			return nil, fmt.Errorf("failed to resolve absolute path: %w", err)
+		}
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid spec %s: %w", specPath, err)
+	}
+	return &s, nil
+}
+
+// validate checks a spec and normalizes its values
+func (s *Spec) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := downloader.GetProvider(s.Type); err != nil {
+		return err
+	}
+	if s.Version == "" {
+		s.Version = downloader.VersionLatest
+	}
+
+	// Proxies have no server.properties, ops or whitelist
+	if downloader.IsProxy(s.Type) && (s.Properties != nil || s.Ops != nil || s.Whitelist != nil) {
+		return fmt.Errorf("%s is a proxy, which has no properties, ops or whitelist", s.Type)
+	}
+
+	// Check the properties like 'mcsrvr props set' does
+	for key, value := range s.Properties {
+		if key == "enable-rcon" || key == "rcon.port" || key == "rcon.password" {
+			return fmt.Errorf("%s is managed by mcsrvr and cannot be set in a spec", key)
+		}
+		normalized, err := properties.Validate(key, value)
+		if err != nil {
+			return err
+		}
+		s.Properties[key] = normalized
+	}
+
+	// Plugins and mods must go where the server type loads them from
+	addonDir := downloader.AddonDir(s.Type)
+	if len(s.Plugins) > 0 && addonDir != "plugins" {
+		return fmt.Errorf("%s servers do not load plugins", s.Type)
+	}
+	if len(s.Mods) > 0 && addonDir != "mods" {
+		return fmt.Errorf("%s servers do not load mods", s.Type)
+	}
+	files := make(map[string]bool)
+	for _, addons := range [][]Addon{s.Plugins, s.Mods} {
+		for i := range addons {
+			if err := addons[i].validate(); err != nil {
+				return err
+			}
+			if files[addons[i].File] {
+				return fmt.Errorf("%s is listed more than once", addons[i].File)
+			}
+			files[addons[i].File] = true
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, schedule := range s.Schedules {
+		if err := scheduler.Validate(schedule.config()); err != nil {
+			return fmt.Errorf("schedule '%s': %w", schedule.Name, err)
+		}
+		if names[schedule.Name] {
+			return fmt.Errorf("schedule '%s' is listed more than once", schedule.Name)
+		}
+		names[schedule.Name] = true
+	}
+
+	if s.Backups != nil && s.Backups.MaxTotalSize != "" {
+		if _, err := backup.ParseSize(s.Backups.MaxTotalSize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validate checks an addon and fills in its file name
+func (a *Addon) validate() error {
+	if a.URL == "" {
+		return fmt.Errorf("plugins and mods need a url")
+	}
+	if a.File == "" {
+		u, err := url.Parse(a.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url %s: %w", a.URL, err)
+		}
+		a.File = path.Base(u.Path)
+	}
+	if a.File != filepath.Base(a.File) || !strings.HasSuffix(a.File, ".jar") {
+		return fmt.Errorf("invalid file name %q for %s, set file to the name of the jar", a.File, a.URL)
+	}
+	return nil
+}
+
+// checksum returns the checksum that verifies an addon, if the spec has one
+func (a Addon) checksum() downloader.Checksum {
+	switch {
+	case a.SHA256 != "":
+		return downloader.Checksum{Algorithm: downloader.ChecksumSHA256, Value: a.SHA256}
+	case a.SHA1 != "":
+		return downloader.Checksum{Algorithm: downloader.ChecksumSHA1, Value: a.SHA1}
+	}
+	return downloader.Checksum{}
+}
+
+// config converts a schedule to the form stored in the server configuration
+func (s Schedule) config() config.Schedule {
+	return config.Schedule{
+		Name:     s.Name,
+		Cron:     s.Cron,
+		Action:   s.Action,
+		Command:  s.Command,
+		Warnings: s.Warnings,
+	}
+}
+
+// retention converts the backup settings to the form stored in the server configuration
+func (b Backups) retention() config.Retention {
+	return config.Retention{
+		KeepLast:     b.KeepLast,
+		KeepDaily:    b.KeepDaily,
+		KeepWeekly:   b.KeepWeekly,
+		MaxTotalSize: b.MaxTotalSize,
+	}
+}
@@ -0,0 +1,129 @@
+// Package store reads and writes the state files of mcsrvr, such as config.json and
+// active_servers.json, safely when several mcsrvr processes use them at the same time.
+// Changes are made under an advisory lock, files are replaced atomically, and the last
+// good version of a JSON file is kept as a .bak that is read if the file is damaged.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// backupSuffix is appended to a JSON file for the copy of its last good version
+const backupSuffix = ".bak"
+
+// FileLock is an exclusive advisory lock on a state file
+type FileLock struct {
+	file *os.File
+}
+
+// Lock takes the exclusive lock of a state file, waiting for other processes to release
+// it. The lock is held on a separate .lock file, so the state file itself can be replaced
+// while it is locked. Locks are not reentrant: a process must not lock a file it already holds.
+func Lock(path string) (*FileLock, error) {
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", filepath.Base(path), err)
+	}
+	return &FileLock{file: file}, nil
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteFile replaces a file atomically. The data is written to a temporary file in the
+// same directory and flushed to disk before it is renamed over the file, so readers see
+// either the old or the new contents, even if mcsrvr or the machine crashes.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// Make the rename itself durable
+	return syncDir(dir)
+}
+
+// LoadJSON reads a JSON file into v. If the file cannot be parsed, its last good version
+// is read from the .bak file instead. A missing file returns an error for which
+// os.IsNotExist is true.
+func LoadJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	parseErr := json.Unmarshal(data, v)
+	if parseErr == nil {
+		return nil
+	}
+
+	// Fall back to the last good version
+	backupData, err := os.ReadFile(path + backupSuffix)
+	if err != nil {
+		return parseErr
+	}
+	if err := json.Unmarshal(backupData, v); err != nil {
+		return parseErr
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s is damaged (%v), using the last good version from %s\n",
+		path, parseErr, filepath.Base(path+backupSuffix))
+	return nil
+}
+
+// SaveJSON writes v to a JSON file atomically. The version it replaces is kept as a .bak
+// file if it is valid JSON, so a damaged file never overwrites the last good version.
+func SaveJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+
+	if current, err := os.ReadFile(path); err == nil && json.Valid(current) {
+		if err := WriteFile(path+backupSuffix, current, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	if err := WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+// Package util holds small helpers shared by the packages and commands of mcsrvr.
+package util
+
+import "fmt"
+
+// FormatSize formats a byte count for display, e.g. "512 B" or "1.5 GiB"
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}